@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+)
+
+func convertCommand(args []string) int {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := flags.String("to", "yaml", "format to convert to: yaml or json")
+	output := flags.String("output", "", "file to write, defaults to stdout")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger convert [options] <config_file>")
+		return 2
+	}
+
+	data, err := config.Convert(flags.Arg(0), *to)
+	if err != nil {
+		fmt.Printf("Error converting configuration: %s\n", err)
+		return 2
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Printf("Error writing %s: %s\n", *output, err)
+		return 2
+	}
+	return 0
+}
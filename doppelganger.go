@@ -1,36 +1,71 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/dsa-ferreira/doppelganger/internal/config"
-	"github.com/dsa-ferreira/doppelganger/internal/server"
+	"strings"
 )
 
-func main() {
-	verbose := flag.Bool("verbose", false, "increase verbosity")
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
 
-	flag.Parse()
+var commands []command
 
-	configFile := flag.Args()[0]
-	servers, err := config.ParseConfiguration(configFile)
-	if err != nil {
-		fmt.Printf("Error parsing configuration: %s\n", err)
-		os.Exit(2)
+func init() {
+	commands = []command{
+		{name: "serve", summary: "start the servers described by a config file", run: serveCommand},
+		{name: "init", summary: "write a starter config file", run: initCommand},
+		{name: "validate", summary: "parse a config file and report errors", run: validateCommand},
+		{name: "routes", summary: "print the routing table without starting servers", run: routesCommand},
+		{name: "suggest", summary: "draft stubs for the requests recorded in a journal or HAR file", run: suggestCommand},
+		{name: "replay", summary: "re-send the requests recorded in a journal or HAR file to a target", run: replayCommand},
+		{name: "har", summary: "convert a journal file to a HAR file", run: harCommand},
+		{name: "hosts", summary: "print an /etc/hosts snippet for the servers' hostnames", run: hostsCommand},
+		{name: "convert", summary: "rewrite a config file as YAML or JSON", run: convertCommand},
+		{name: "schema", summary: "print the config JSON schema", run: schemaCommand},
+		{name: "version", summary: "print the doppelganger version", run: versionCommand},
+		{name: "help", summary: "show this help", run: helpCommand},
 	}
+}
 
-	for i := 0; i < len(servers.Configurations); i++ {
-		go server.StartServer(&servers.Configurations[i], *verbose)
+func main() {
+	args := os.Args[1:]
+	if code, ok := runService(args); ok {
+		os.Exit(code)
 	}
 
-	gracefulShutdown := make(chan os.Signal, 1)
-	signal.Notify(gracefulShutdown, syscall.SIGINT, syscall.SIGTERM)
+	if len(args) > 0 {
+		if args[0] == "-version" || args[0] == "--version" {
+			os.Exit(versionCommand(args[1:]))
+		}
+		if cmd, ok := findCommand(args[0]); ok {
+			os.Exit(cmd.run(args[1:]))
+		}
+	}
 
-	<-gracefulShutdown
+	// Plain `doppelganger [flags] <json_file>` keeps working as an alias for serve.
+	os.Exit(serveCommand(args))
+}
 
-	fmt.Printf("Shuting down")
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func helpCommand(args []string) int {
+	var builder strings.Builder
+	builder.WriteString("Usage: doppelganger <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		builder.WriteString(fmt.Sprintf("  %-10s %s\n", cmd.name, cmd.summary))
+	}
+	builder.WriteString("\nRunning `doppelganger [options] <json_file>` is the same as `doppelganger serve`.\n")
+	fmt.Print(builder.String())
+	return 0
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+)
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// variablesFlag collects name=value overrides of config variables. Values
+// may hold commas, so they are not split like lists.
+type variablesFlag map[string]string
+
+func (v variablesFlag) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v variablesFlag) Set(value string) error {
+	name, variable, found := strings.Cut(value, "=")
+	if !found || name == "" {
+		return errors.New("variable must look like name=value, got " + value)
+	}
+	v[name] = variable
+	return nil
+}
+
+type loadOptions struct {
+	profiles  listFlag
+	ports     listFlag
+	only      listFlag
+	variables variablesFlag
+	fixtures  *string
+}
+
+func registerLoadFlags(flags *flag.FlagSet) *loadOptions {
+	options := &loadOptions{variables: variablesFlag{}}
+	flags.Var(&options.profiles, "profile", "comma separated list of active profiles")
+	flags.Var(&options.ports, "port", "override a server port, as name=port (repeatable)")
+	flags.Var(&options.only, "only", "comma separated list of server names to start")
+	flags.Var(options.variables, "var", "override a config variable, as name=value (repeatable)")
+	options.fixtures = flags.String("fixtures-dir", "", "resolve relative FILE, PAGINATE and GRAPHQL paths against this directory instead of the config file's")
+	return options
+}
+
+type loadReport struct {
+	file      string
+	servers   int
+	endpoints int
+	mappings  int
+	elapsed   time.Duration
+	issues    []config.Issue
+	files     []string
+	hash      string
+	loadedAt  time.Time
+}
+
+func (report loadReport) String() string {
+	return fmt.Sprintf("Loaded %d servers, %d endpoints, %d mappings from %s in %s",
+		report.servers, report.endpoints, report.mappings, report.file, report.elapsed.Round(time.Millisecond))
+}
+
+func newLoadReport(file string, servers *config.Servers, elapsed time.Duration) loadReport {
+	report := loadReport{file: file, servers: len(servers.Configurations), elapsed: elapsed, files: servers.Files, hash: configHash(servers), loadedAt: time.Now()}
+	for _, configuration := range servers.Configurations {
+		report.endpoints += len(configuration.Endpoints)
+		for _, endpoint := range configuration.Endpoints {
+			report.mappings += len(endpoint.Mappings)
+		}
+	}
+	return report
+}
+
+// configHash fingerprints the loaded configuration, after variables,
+// includes and overrides, so instances can be told apart by what they
+// actually serve rather than by the file they were started with.
+func configHash(servers *config.Servers) string {
+	data, err := json.Marshal(servers)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadConfiguration(configFile string, options *loadOptions) (*config.Servers, loadReport, error) {
+	start := time.Now()
+	servers, err := config.ParseConfigurationWithOptions(configFile, config.Options{Variables: options.variables, FixturesDir: *options.fixtures})
+	if err != nil {
+		return nil, loadReport{}, err
+	}
+
+	servers.ApplyProfiles(options.profiles)
+
+	for _, override := range options.ports {
+		name, rawPort, found := strings.Cut(override, "=")
+		if !found {
+			return nil, loadReport{}, errors.New("port override must look like name=port, got " + override)
+		}
+		port, err := strconv.Atoi(rawPort)
+		if err != nil {
+			return nil, loadReport{}, errors.New("invalid port in override " + override)
+		}
+		if err := servers.OverridePort(name, port); err != nil {
+			return nil, loadReport{}, err
+		}
+	}
+
+	if len(options.only) > 0 {
+		if err := servers.Only(options.only); err != nil {
+			return nil, loadReport{}, err
+		}
+	}
+
+	report := newLoadReport(configFile, servers, time.Since(start))
+	report.issues, err = config.Lint(configFile)
+	if err != nil {
+		return nil, loadReport{}, err
+	}
+	return servers, report, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsa-ferreira/doppelganger/internal/har"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+)
+
+func harCommand(args []string) int {
+	flags := flag.NewFlagSet("har", flag.ExitOnError)
+	output := flags.String("output", "", "write the HAR file here instead of stdout")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger har [options] <journal_file>")
+		return 2
+	}
+
+	entries, err := journal.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading journal: %s\n", err)
+		return 2
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			fmt.Printf("Error creating HAR file: %s\n", err)
+			return 2
+		}
+		defer out.Close()
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(har.FromJournal(entries, buildInfo().Version)); err != nil {
+		fmt.Printf("Error writing HAR file: %s\n", err)
+		return 2
+	}
+	return 0
+}
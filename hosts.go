@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+)
+
+func hostsCommand(args []string) int {
+	flags := flag.NewFlagSet("hosts", flag.ExitOnError)
+	address := flags.String("address", "127.0.0.1", "address the hostnames resolve to")
+	loadFlags := registerLoadFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger hosts [options] <json_file>")
+		return 2
+	}
+
+	servers, _, err := loadConfiguration(flags.Arg(0), loadFlags)
+	if err != nil {
+		fmt.Printf("Error parsing configuration: %s\n", err)
+		return 2
+	}
+
+	printHosts(os.Stdout, servers, *address, flags.Arg(0))
+	return 0
+}
+
+// printHosts writes an /etc/hosts snippet pointing every server's hostnames
+// at address.
+func printHosts(out io.Writer, servers *config.Servers, address string, file string) {
+	fmt.Fprintf(out, "# doppelganger %s\n", file)
+
+	var seen []string
+	for _, configuration := range servers.Configurations {
+		for _, host := range configuration.Hosts {
+			if slices.Contains(seen, host) {
+				continue
+			}
+			seen = append(seen, host)
+			fmt.Fprintf(out, "%s\t%s\t# %s:%d\n", address, host, configuration.Name, configuration.Port)
+		}
+	}
+
+	fmt.Fprintln(out, "# end doppelganger")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+type initOptions struct {
+	Port     int
+	BasePath string
+}
+
+var starterTemplate = template.Must(template.New("starter").Parse(`{
+  "$comment": "Starter doppelganger config. Run it with: doppelganger serve <this file>",
+  "servers": [
+    {
+      "port": {{.Port}},
+      "endpoint": [
+        {
+          "$comment": "Mappings without params always match.",
+          "path": "{{.BasePath}}/health",
+          "verb": "GET",
+          "mappings": [
+            {
+              "content": {
+                "data": { "status": "UP" }
+              }
+            }
+          ]
+        },
+        {
+          "$comment": "Mappings are tried in order; the first one whose params all evaluate to true answers.",
+          "path": "{{.BasePath}}/users/:id",
+          "verb": "GET",
+          "mappings": [
+            {
+              "$comment": "EQUALS compares two expressions of the same kind. PATH reads the :id path param.",
+              "params": [
+                {
+                  "type": "EQUALS",
+                  "left": { "type": "PATH", "id": "id" },
+                  "right": { "type": "STRING", "value": "1" }
+                }
+              ],
+              "code": 200,
+              "content": {
+                "data": { "id": 1, "name": "John" }
+              }
+            },
+            {
+              "$comment": "Fallback for every other id.",
+              "code": 404,
+              "content": {
+                "data": { "error": "user not found" }
+              }
+            }
+          ]
+        },
+        {
+          "path": "{{.BasePath}}/users",
+          "verb": "POST",
+          "mappings": [
+            {
+              "$comment": "AND/OR/NOT combine boolean expressions. BODY reads a JSON or form field, QUERY a query param, REGEX matches a pattern.",
+              "params": [
+                {
+                  "type": "AND",
+                  "expressions": [
+                    {
+                      "type": "REGEX",
+                      "value": { "type": "BODY", "id": "email" },
+                      "pattern": "^[^@]+@[^@]+$"
+                    },
+                    {
+                      "type": "NOT",
+                      "expression": {
+                        "type": "EQUALS",
+                        "left": { "type": "QUERY", "id": "dryRun" },
+                        "right": { "type": "STRING", "value": "true" }
+                      }
+                    }
+                  ]
+                }
+              ],
+              "code": 201,
+              "content": {
+                "data": { "message": "User created" }
+              }
+            },
+            {
+              "code": 400,
+              "content": {
+                "data": { "error": "invalid email" }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`))
+
+func initCommand(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	output := flags.String("output", "doppelganger.json", "file to write the starter config to")
+	port := flags.Int("port", 8000, "port for the starter server")
+	basePath := flags.String("base-path", "/api", "path prefix for the example endpoints")
+	interactive := flags.Bool("interactive", false, "prompt for the options instead of using flags")
+	force := flags.Bool("force", false, "overwrite the output file if it exists")
+	flags.Parse(args)
+
+	options := initOptions{Port: *port, BasePath: *basePath}
+	if *interactive {
+		var err error
+		options, *output, err = promptInitOptions(os.Stdin, options, *output)
+		if err != nil {
+			fmt.Printf("Error reading answers: %s\n", err)
+			return 2
+		}
+	}
+	options.BasePath = "/" + strings.Trim(options.BasePath, "/")
+	if options.BasePath == "/" {
+		options.BasePath = ""
+	}
+
+	if _, err := os.Stat(*output); err == nil && !*force {
+		fmt.Printf("%s already exists, use -force to overwrite it\n", *output)
+		return 2
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Printf("Error creating %s: %s\n", *output, err)
+		return 2
+	}
+	defer file.Close()
+
+	if err := starterTemplate.Execute(file, options); err != nil {
+		fmt.Printf("Error writing %s: %s\n", *output, err)
+		return 2
+	}
+
+	fmt.Printf("Wrote starter config to %s, start it with: doppelganger serve %s\n", *output, *output)
+	return 0
+}
+
+func promptInitOptions(input io.Reader, defaults initOptions, output string) (initOptions, string, error) {
+	reader := bufio.NewReader(input)
+
+	answer, err := prompt(reader, "Port", strconv.Itoa(defaults.Port))
+	if err != nil {
+		return defaults, output, err
+	}
+	port, err := strconv.Atoi(answer)
+	if err != nil {
+		return defaults, output, errors.New("port must be a number")
+	}
+	defaults.Port = port
+
+	if defaults.BasePath, err = prompt(reader, "Base path", defaults.BasePath); err != nil {
+		return defaults, output, err
+	}
+	if output, err = prompt(reader, "Output file", output); err != nil {
+		return defaults, output, err
+	}
+
+	return defaults, output, nil
+}
+
+func prompt(reader *bufio.Reader, question string, fallback string) (string, error) {
+	fmt.Printf("%s [%s]: ", question, fallback)
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return fallback, nil
+	}
+	return line, nil
+}
@@ -0,0 +1,410 @@
+package admin
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/events"
+	"github.com/dsa-ferreira/doppelganger/internal/har"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/kv"
+	"github.com/dsa-ferreira/doppelganger/internal/metrics"
+	"github.com/dsa-ferreira/doppelganger/internal/quotas"
+	"github.com/dsa-ferreira/doppelganger/internal/scenarios"
+	"github.com/dsa-ferreira/doppelganger/internal/smtp"
+	"github.com/dsa-ferreira/doppelganger/internal/supervisor"
+	"github.com/dsa-ferreira/doppelganger/internal/toggles"
+	"github.com/dsa-ferreira/doppelganger/internal/uploads"
+	"github.com/dsa-ferreira/doppelganger/internal/usage"
+	"github.com/dsa-ferreira/doppelganger/internal/variants"
+	"github.com/dsa-ferreira/doppelganger/internal/verify"
+	"github.com/gin-gonic/gin"
+)
+
+const prefix = "/__admin"
+
+// ui is a dashboard polling the admin API, for those not fond of curl.
+//
+//go:embed ui/index.html
+var ui []byte
+
+type Options struct {
+	Metrics   *metrics.Registry
+	Mailbox   *smtp.Mailbox
+	Journal   *journal.MemoryRecorder
+	Events    *events.Queue
+	Usage     *usage.Tracker
+	Scenarios *scenarios.Store
+	Uploads   *uploads.Store
+	Toggles   *toggles.Store
+	Quotas    *quotas.Store
+	Variants  *variants.Store
+	KV        *kv.Store
+	Servers   *supervisor.Supervisor
+	Info      Info
+	Build     Build
+}
+
+// Build tells which binary is running, as recorded at build time.
+type Build struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion,omitempty"`
+}
+
+// Info tells which binary and config a running instance has.
+type Info struct {
+	Version    string    `json:"version"`
+	Files      []string  `json:"files"`
+	ConfigHash string    `json:"configHash"`
+	LoadedAt   time.Time `json:"loadedAt"`
+}
+
+// Listen binds the admin API on its own port and serves it in the
+// background until the returned closer is called.
+func Listen(port int, options Options) (io.Closer, error) {
+	r := gin.Default()
+
+	api := r.Group(prefix)
+	api.GET("/ui", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", ui)
+	})
+	api.GET("/info", func(c *gin.Context) {
+		c.JSON(http.StatusOK, options.Info)
+	})
+	api.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, options.Build)
+	})
+	api.GET("/metrics", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"latency": options.Metrics.Snapshot(), "journal": options.Journal.Stats()})
+	})
+	api.GET("/usage", func(c *gin.Context) {
+		if c.Query("unused") == "true" {
+			c.JSON(http.StatusOK, gin.H{"mappings": options.Usage.Unused()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"mappings": options.Usage.Report()})
+	})
+	api.POST("/verify", func(c *gin.Context) {
+		var request verify.Request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		result := verify.Verify(options.Journal.Entries(), request)
+		if !result.Passed {
+			c.JSON(http.StatusExpectationFailed, result)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+	api.GET("/scenarios", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"scenarios": filterStates(options.Scenarios.States(), c)})
+	})
+	api.PUT("/scenarios/:name", func(c *gin.Context) {
+		var body struct {
+			Client string `json:"client"`
+			State  string `json:"state" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		options.Scenarios.SetState(body.Client, c.Param("name"), body.State)
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/scenarios", func(c *gin.Context) {
+		client, one := c.GetQuery("client")
+		options.Scenarios.Reset(client, !one)
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/toggles", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"disabled": options.Toggles.Disabled()})
+	})
+	api.PUT("/toggles", func(c *gin.Context) {
+		var body struct {
+			toggles.Toggle
+			Enabled *bool `json:"enabled" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if body.Endpoint == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "toggles require an endpoint"})
+			return
+		}
+		options.Toggles.Set(body.Toggle, *body.Enabled)
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/toggles", func(c *gin.Context) {
+		options.Toggles.Reset()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/quotas", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"quotas": options.Quotas.Usage()})
+	})
+	api.DELETE("/quotas", func(c *gin.Context) {
+		client, one := c.GetQuery("client")
+		options.Quotas.Reset(c.Query("name"), client, !one)
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/variants", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"pins": options.Variants.Pins()})
+	})
+	api.PUT("/variants/:client", func(c *gin.Context) {
+		var body struct {
+			Variant string `json:"variant" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		options.Variants.Pin(c.Param("client"), body.Variant)
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/variants/:client", func(c *gin.Context) {
+		options.Variants.Unpin(c.Param("client"))
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/variants", func(c *gin.Context) {
+		options.Variants.Clear()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/kv", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"values": options.KV.All()})
+	})
+	api.GET("/kv/:key", func(c *gin.Context) {
+		value, ok := options.KV.Get(c.Param("key"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no value for key " + c.Param("key")})
+			return
+		}
+		c.JSON(http.StatusOK, value)
+	})
+	api.PUT("/kv/:key", func(c *gin.Context) {
+		var value any
+		if err := c.ShouldBindJSON(&value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		options.KV.Set(c.Param("key"), value)
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/kv/:key", func(c *gin.Context) {
+		options.KV.Delete(c.Param("key"))
+		c.Status(http.StatusNoContent)
+	})
+	api.DELETE("/kv", func(c *gin.Context) {
+		options.KV.Clear()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/servers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"servers": options.Servers.Statuses()})
+	})
+	for action, apply := range map[string]func(string) error{
+		"start":    options.Servers.Start,
+		"stop":     options.Servers.Stop,
+		"restart":  options.Servers.Restart,
+		"failover": options.Servers.Failover,
+		"failback": options.Servers.Failback,
+	} {
+		api.POST("/servers/:name/"+action, func(c *gin.Context) {
+			err := apply(c.Param("name"))
+			if errors.Is(err, supervisor.ErrUnknown) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error() + " " + c.Param("name")})
+				return
+			} else if errors.Is(err, supervisor.ErrNoStandby) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.Status(http.StatusNoContent)
+		})
+	}
+	api.POST("/servers/:name/outage", func(c *gin.Context) {
+		body := struct {
+			Mode     supervisor.OutageMode `json:"mode"`
+			Duration int                   `json:"duration" binding:"required,gt=0"`
+		}{Mode: supervisor.OutageReset}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		err := options.Servers.Outage(c.Param("name"), body.Mode, time.Duration(body.Duration)*time.Millisecond)
+		if errors.Is(err, supervisor.ErrUnknown) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error() + " " + c.Param("name")})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/uploads", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"uploads": options.Uploads.Uploads()})
+	})
+	api.GET("/uploads/:id/content", func(c *gin.Context) {
+		content, ok := options.Uploads.Content(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": uploads.ErrUnknown.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/octet-stream", content)
+	})
+	api.DELETE("/uploads", func(c *gin.Context) {
+		options.Uploads.Clear()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/journal", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"entries": filterEntries(options.Journal.Entries(), c), "dropped": options.Journal.Stats().Dropped})
+	})
+	api.GET("/journal/har", func(c *gin.Context) {
+		c.Header("Content-Disposition", `attachment; filename="journal.har"`)
+		c.JSON(http.StatusOK, har.FromJournal(filterEntries(options.Journal.Entries(), c), options.Build.Version))
+	})
+	api.DELETE("/journal", func(c *gin.Context) {
+		options.Journal.Clear()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/messages", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"messages": filterMessages(options.Mailbox.Messages(), c)})
+	})
+	api.DELETE("/messages", func(c *gin.Context) {
+		options.Mailbox.Clear()
+		c.Status(http.StatusNoContent)
+	})
+
+	api.GET("/events", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"events": filterEvents(options.Events.Events(), c)})
+	})
+	api.DELETE("/events", func(c *gin.Context) {
+		options.Events.Clear()
+		c.Status(http.StatusNoContent)
+	})
+	api.GET("/events/stream", func(c *gin.Context) {
+		streamEvents(c, options.Events)
+	})
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: r}
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+		}
+	}()
+	log.Printf("Admin API listening on :%d%s\n", port, prefix)
+	return server, nil
+}
+
+// filterMessages keeps the messages matching the server, to and subject
+// query params; to and subject match substrings.
+func filterMessages(messages []smtp.Message, c *gin.Context) []smtp.Message {
+	server, to, subject := c.Query("server"), c.Query("to"), c.Query("subject")
+
+	filtered := make([]smtp.Message, 0, len(messages))
+	for _, message := range messages {
+		if server != "" && message.Server != server {
+			continue
+		}
+		if to != "" && !slices.ContainsFunc(message.To, func(recipient string) bool { return strings.Contains(recipient, to) }) {
+			continue
+		}
+		if subject != "" && !strings.Contains(message.Subject, subject) {
+			continue
+		}
+		filtered = append(filtered, message)
+	}
+	return filtered
+}
+
+// filterEntries keeps the journal entries matching the server, protocol
+// (HTTP for requests), method, path and matched query params.
+func filterEntries(entries []journal.Entry, c *gin.Context) []journal.Entry {
+	server, protocol, method, path := c.Query("server"), c.Query("protocol"), c.Query("method"), c.Query("path")
+	matched := c.Query("matched")
+
+	filtered := make([]journal.Entry, 0, len(entries))
+	for _, entry := range entries {
+		entryProtocol := entry.Protocol
+		if entryProtocol == "" {
+			entryProtocol = "HTTP"
+		}
+		if server != "" && entry.Server != server ||
+			protocol != "" && !strings.EqualFold(entryProtocol, protocol) ||
+			method != "" && !strings.EqualFold(entry.Method, method) ||
+			path != "" && entry.Path != path ||
+			matched != "" && strconv.FormatBool(entry.Matched) != matched {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// filterEvents keeps the events matching the server and topic query params.
+func filterEvents(published []events.Event, c *gin.Context) []events.Event {
+	server, topic := c.Query("server"), c.Query("topic")
+
+	filtered := make([]events.Event, 0, len(published))
+	for _, event := range published {
+		if server != "" && event.Server != server || topic != "" && event.Topic != topic {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
+// streamEvents sends the events published from now on as server-sent
+// events, named after their topic, until the client goes away.
+func streamEvents(c *gin.Context, queue *events.Queue) {
+	received, cancel := queue.Subscribe()
+	defer cancel()
+
+	server, topic := c.Query("server"), c.Query("topic")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-received:
+			if server != "" && event.Server != server || topic != "" && event.Topic != topic {
+				return true
+			}
+			c.SSEvent(event.Topic, event)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// filterStates keeps the scenario states of the client query param.
+func filterStates(states []scenarios.State, c *gin.Context) []scenarios.State {
+	client, ok := c.GetQuery("client")
+	if !ok {
+		return states
+	}
+
+	filtered := make([]scenarios.State, 0, len(states))
+	for _, state := range states {
+		if state.Client == client {
+			filtered = append(filtered, state)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Activation is a point in time given either as an RFC3339 timestamp or as
+// a duration after the server started, like "90s".
+type Activation struct {
+	At    time.Time
+	After time.Duration
+}
+
+// Resolve returns the point in time for a server started at started.
+func (activation Activation) Resolve(started time.Time) time.Time {
+	if activation.At.IsZero() {
+		return started.Add(activation.After)
+	}
+	return activation.At
+}
+
+func (activation Activation) MarshalJSON() ([]byte, error) {
+	if activation.At.IsZero() {
+		return json.Marshal(activation.After.String())
+	}
+	return json.Marshal(activation.At.Format(time.RFC3339))
+}
+
+func (activation *Activation) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return atBlock(data, err)
+	}
+
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		*activation = Activation{At: at}
+		return nil
+	}
+	after, err := time.ParseDuration(value)
+	if err != nil || after < 0 {
+		return atBlock(data, errors.New("activation time must be an RFC3339 timestamp or a positive duration, got "+value))
+	}
+	*activation = Activation{After: after}
+	return nil
+}
+
+// Active tells whether the mapping answers at now for a server started at
+// started.
+func (mapping Mapping) Active(started time.Time, now time.Time) bool {
+	if mapping.ActiveFrom != nil && now.Before(mapping.ActiveFrom.Resolve(started)) {
+		return false
+	}
+	if mapping.ActiveUntil != nil && !now.Before(mapping.ActiveUntil.Resolve(started)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// verifyChecksums checks the FILE contents declaring a sha256 against the
+// files on disk, so stale or corrupted fixtures fail the startup.
+func verifyChecksums(configuration *Configuration) error {
+	for _, endpoint := range configuration.Endpoints {
+		for i := range endpoint.Mappings {
+			err := endpoint.Mappings[i].WalkContents(func(content *Content) error {
+				file, ok := content.Data.(DataFile)
+				if content.Type != ContentTypeFile || !ok || file.SHA256 == "" {
+					return nil
+				}
+				return verifyChecksum(file)
+			})
+			if err != nil {
+				return fmt.Errorf("FILE content of %s %s: %w", endpoint.Verb, endpoint.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func verifyChecksum(file DataFile) error {
+	data, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, data); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, file.SHA256) {
+		return fmt.Errorf("%s has sha256 %s, expected %s", file.Path, sum, file.SHA256)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// validateStatus checks a final status code, which may be one the RFCs do
+// not define as long as it has three digits, and its reason phrase.
+func validateStatus(code int, reason string) error {
+	if code < 100 || code > 999 {
+		return fmt.Errorf("code %d must have three digits", code)
+	}
+	if code < 200 {
+		return fmt.Errorf("code %d is not a final status, send 1xx responses as interim", code)
+	}
+	if strings.ContainsFunc(reason, func(r rune) bool { return r < ' ' && r != '\t' || r == 0x7f }) {
+		return errors.New("reason cannot hold control characters")
+	}
+	return nil
+}
+
+// Bodiless tells whether responses with that code never carry a body:
+// informational ones, 204 and 304.
+func Bodiless(code int) bool {
+	return code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified
+}
+
+// bodilessContent reports mappings and variants whose code answers without
+// a body while declaring content, which clients would never get.
+func (l *linter) bodilessContent() {
+	type response struct {
+		Code    *int            `json:"code"`
+		Content json.RawMessage `json:"content"`
+	}
+	for _, endpoint := range l.endpoints() {
+		for i, raw := range endpoint.Mappings {
+			var mapping struct {
+				response
+				ID       string            `json:"id"`
+				Variants []json.RawMessage `json:"variants"`
+			}
+			if json.Unmarshal(raw, &mapping) != nil {
+				continue
+			}
+			if mapping.Code != nil && Bodiless(*mapping.Code) && mapping.Content != nil {
+				l.report(max(blockOffset(l.src.data, raw), 0), fmt.Sprintf("mapping %s answers %d, which has no body, so its content is never sent", Mapping{ID: mapping.ID}.Label(i), *mapping.Code))
+			}
+			for _, rawVariant := range mapping.Variants {
+				var variant struct {
+					response
+					Name string `json:"name"`
+				}
+				if json.Unmarshal(rawVariant, &variant) != nil {
+					continue
+				}
+				if variant.Code != nil && Bodiless(*variant.Code) && variant.Content != nil {
+					l.report(max(blockOffset(l.src.data, rawVariant), 0), fmt.Sprintf("variant %s answers %d, which has no body, so its content is never sent", variant.Name, *variant.Code))
+				}
+			}
+		}
+	}
+}
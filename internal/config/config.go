@@ -1,16 +1,31 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strconv"
+	"time"
 
-	"github.com/dsa-ferreira/doppelganger/internal/expressions"
+	"github.com/dsa-ferreira/doppelganger/internal/graphql"
+	"github.com/dsa-ferreira/doppelganger/internal/signing"
+	"github.com/dsa-ferreira/doppelganger/internal/templating"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
 )
 
 type Servers struct {
 	Configurations []Configuration `json:"servers"`
+	// Files are the config file and the files it includes.
+	Files []string `json:"-"`
 }
 
 func (servers *Servers) UnmarshalJSON(data []byte) error {
@@ -32,21 +47,151 @@ func (servers *Servers) UnmarshalJSON(data []byte) error {
 }
 
 type Configuration struct {
-	Endpoints []Endpoint `json:"endpoint"`
-	Port      int        `json:"port"`
+	Name string `json:"name,omitempty"`
+	// Type is the protocol served, HTTP unless told otherwise.
+	Type string `json:"type,omitempty"`
+	Port int    `json:"port"`
+	// Hosts are the hostnames clients use to reach this server, see the hosts command.
+	Hosts    []string `json:"hosts,omitempty"`
+	Includes []string `json:"include,omitempty"`
+	// Scenarios are files of ordered steps, compiled into scenario mappings.
+	Scenarios []string `json:"scenarios,omitempty"`
+	// Datasets are tables of records available to templates and PAGINATE
+	// contents, keyed by name.
+	Datasets map[string]*Dataset `json:"datasets,omitempty"`
+	// StandbyFor names the primary server this one takes over from when
+	// failing over through the admin API. Until then it is not started.
+	StandbyFor string `json:"standbyFor,omitempty"`
+	// ClientKey partitions scenario states by the value it evaluates to,
+	// e.g. a header identifying the test worker.
+	ClientKey expressions.Expression `json:"clientKey,omitempty"`
+
+	DebugHeaders bool              `json:"debugHeaders,omitempty"`
+	BodyParsers  map[string]string `json:"bodyParsers,omitempty"`
+	TLS          *TLS              `json:"tls,omitempty"`
+	HTTP3        bool              `json:"http3,omitempty"`
+	// Idempotency replays the first response to requests repeating an
+	// idempotency key.
+	Idempotency *Idempotency `json:"idempotency,omitempty"`
+	// Mirror sends a copy of every request to a real service, answering
+	// with the mock response all the same.
+	Mirror *Mirror `json:"mirror,omitempty"`
+	// Redact masks secrets in the journal and verbose logs of every
+	// endpoint.
+	Redact *Redact `json:"redact,omitempty"`
+	// KeepAlive, true when nil, lets clients reuse connections, for at most
+	// MaxRequestsPerConnection requests when positive.
+	KeepAlive                *bool `json:"keepAlive,omitempty"`
+	MaxRequestsPerConnection int   `json:"maxRequestsPerConnection,omitempty"`
+	// MaxConcurrentRequests, when positive, caps the requests handled at
+	// once. Up to MaxQueuedRequests more wait for a slot, the others are
+	// answered with a 503 telling to retry after RetryAfter seconds, 1 by
+	// default.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests,omitempty"`
+	MaxQueuedRequests     int `json:"maxQueuedRequests,omitempty"`
+	RetryAfter            int `json:"retryAfter,omitempty"`
+
+	// Greeting is sent to TCP clients as soon as they connect.
+	Greeting *Payload  `json:"greeting,omitempty"`
+	Rules    []TCPRule `json:"rules,omitempty"`
+
+	Endpoints []Endpoint `json:"endpoint,omitempty"`
+}
+
+const (
+	ServerTypeHTTP = "HTTP"
+	ServerTypeTCP  = "TCP"
+	ServerTypeSMTP = "SMTP"
+	ServerTypeUDP  = "UDP"
+)
+
+type TLS struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// Idempotency keys are forgotten TTL milliseconds after their first
+// request, or never when it is 0, and at most MaxKeys of them are kept,
+// forgetting the oldest first.
+type Idempotency struct {
+	Header  string `json:"header"`
+	TTL     int    `json:"ttl,omitempty"`
+	MaxKeys int    `json:"maxKeys,omitempty"`
+}
+
+// Redact masks the values of the named headers and of the body fields at
+// the given paths, dot separated from the root (e.g. "card.number"), before
+// requests and responses are journaled or logged. Top level fields also
+// cover form fields and query params of that name.
+type Redact struct {
+	Headers []string `json:"headers,omitempty"`
+	Fields  []string `json:"fields,omitempty"`
+}
+
+func (idempotency *Idempotency) UnmarshalJSON(data []byte) error {
+	type Alias Idempotency
+	aux := &Alias{Header: "Idempotency-Key", MaxKeys: 10000}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+	if aux.TTL < 0 || aux.MaxKeys < 0 {
+		return atBlock(data, errors.New("idempotency ttl and maxKeys cannot be negative"))
+	}
+	*idempotency = Idempotency(*aux)
+	return nil
+}
+
+// Mirror is where shadow copies of the requests go. Compare logs the
+// responses of the real service that differ from the mock ones.
+type Mirror struct {
+	URL     string   `json:"url"`
+	Target  *url.URL `json:"-"`
+	Compare bool     `json:"compare,omitempty"`
+	// Timeout is how long, in milliseconds, to wait for the real service.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+func (mirror *Mirror) UnmarshalJSON(data []byte) error {
+	type Alias Mirror
+	aux := &Alias{Timeout: 10000}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+	target, err := url.Parse(aux.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return atBlock(data, errors.New("mirror url must be an absolute http or https URL, got "+aux.URL))
+	}
+	if aux.Timeout <= 0 {
+		return atBlock(data, errors.New("mirror timeout must be positive"))
+	}
+	aux.Target = target
+	*mirror = Mirror(*aux)
+	return nil
 }
 
 func (configuration *Configuration) UnmarshalJSON(data []byte) error {
 	type Alias Configuration
 	type Aux struct {
-		Port *int `json:"port"`
+		Port      *int            `json:"port"`
+		ClientKey json.RawMessage `json:"clientKey"`
 		*Alias
 	}
 
 	aux := &Aux{Alias: (*Alias)(configuration)}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if aux.ClientKey != nil {
+		clientKey, err := expressions.Parse(aux.ClientKey)
+		if err != nil {
+			return inBlock(data, aux.ClientKey, fmt.Errorf("error building clientKey: %w", err))
+		}
+		if clientKey.ReturnType() != reflect.String {
+			return inBlock(data, aux.ClientKey, errors.New("clientKey must evaluate to a string"))
+		}
+		configuration.ClientKey = clientKey
 	}
 
 	if aux.Port == nil {
@@ -55,12 +200,47 @@ func (configuration *Configuration) UnmarshalJSON(data []byte) error {
 		configuration.Port = *aux.Port
 	}
 
+	if configuration.TLS != nil && (configuration.TLS.CertFile == "" || configuration.TLS.KeyFile == "") {
+		return atBlock(data, errors.New("tls requires certFile and keyFile"))
+	}
+	if configuration.HTTP3 && configuration.TLS == nil {
+		return atBlock(data, errors.New("http3 requires tls to be configured"))
+	}
+
+	switch configuration.Type {
+	case "", ServerTypeHTTP:
+		if len(configuration.Rules) > 0 || configuration.Greeting != nil {
+			return atBlock(data, errors.New("rules and greeting are only used by TCP servers"))
+		}
+	case ServerTypeTCP:
+		if len(configuration.Endpoints) > 0 {
+			return atBlock(data, errors.New("TCP servers answer with rules, not endpoints"))
+		}
+		for _, rule := range configuration.Rules {
+			if rule.Close != "" && rule.Close != "graceful" && rule.Close != "reset" {
+				return atBlock(data, errors.New("rule close must be graceful or reset, got "+rule.Close))
+			}
+		}
+	case ServerTypeSMTP, ServerTypeUDP:
+		if len(configuration.Endpoints) > 0 || len(configuration.Rules) > 0 || configuration.Greeting != nil {
+			return atBlock(data, errors.New(configuration.Type+" servers take no endpoints, rules or greeting"))
+		}
+	default:
+		return atBlock(data, errors.New("unknown server type "+configuration.Type))
+	}
+
 	return nil
 }
 
 type Endpoint struct {
-	Path     string    `json:"path"`
-	Verb     string    `json:"verb"`
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Path        string   `json:"path"`
+	Verb        string   `json:"verb"`
+	Profiles    []string `json:"profiles,omitempty"`
+	// Redact adds to the redaction rules of the server for this endpoint.
+	Redact   *Redact   `json:"redact,omitempty"`
 	Mappings []Mapping `json:"mappings"`
 }
 
@@ -73,8 +253,8 @@ func (endpoint *Endpoint) UnmarshalJSON(data []byte) error {
 
 	aux := &Aux{Alias: (*Alias)(endpoint)}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
 	}
 
 	if aux.Verb == nil {
@@ -87,46 +267,352 @@ func (endpoint *Endpoint) UnmarshalJSON(data []byte) error {
 }
 
 type Mapping struct {
-	Params   []expressions.Expression `json:"params"`
-	RespCode int                      `json:"code"`
-	Content  Content                  `json:"content"`
+	ID          string                   `json:"id,omitempty"`
+	Name        string                   `json:"name,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Params      []expressions.Expression `json:"params,omitempty"`
+	RespCode    int                      `json:"code"`
+	// Reason replaces the reason phrase of the status line, on HTTP/1.x
+	// only as later versions have none.
+	Reason   string            `json:"reason,omitempty"`
+	Content  Content           `json:"content"`
+	Profiles []string          `json:"profiles,omitempty"`
+	Trailers map[string]string `json:"trailers,omitempty"`
+	Delay    int               `json:"delay,omitempty"`
+	// Values are evaluated once the mapping matches and exposed to templates.
+	Values map[string]expressions.Expression `json:"values,omitempty"`
+	// Publish lists events appended to the event queue once answered.
+	Publish []Publish `json:"publish,omitempty"`
+	// Scenario gates the mapping on the scenario being in RequiredState,
+	// moving it to NewState once the mapping answered.
+	Scenario      string `json:"scenario,omitempty"`
+	RequiredState string `json:"requiredState,omitempty"`
+	NewState      string `json:"newState,omitempty"`
+	// Signature signs the response body, like a webhook sender would.
+	Signature *Signature `json:"signature,omitempty"`
+	// ContentLength forces the Content-Length header, even to a wrong value.
+	// TransferEncoding forces "chunked" encoding, or "identity" to send no
+	// length at all and close the connection after the body.
+	ContentLength    *int64 `json:"contentLength,omitempty"`
+	TransferEncoding string `json:"transferEncoding,omitempty"`
+	// Bandwidth throttles the body to that many bytes per second.
+	Bandwidth int `json:"bandwidth,omitempty"`
+	// CloseConnection closes the connection once the response is sent.
+	CloseConnection bool `json:"closeConnection,omitempty"`
+	// Interim responses, such as 103 Early Hints, are sent before the
+	// final one.
+	Interim []Interim `json:"interim,omitempty"`
+	// ActiveFrom and ActiveUntil limit when the mapping answers, e.g. to
+	// simulate a maintenance window.
+	ActiveFrom  *Activation `json:"activeFrom,omitempty"`
+	ActiveUntil *Activation `json:"activeUntil,omitempty"`
+	// Quota lets the mapping answer a number of requests per client, then
+	// answers for it with an exhausted response.
+	Quota *Quota `json:"quota,omitempty"`
+	// Variants are alternative responses, each client always getting the
+	// same one, as picked by the value VariantKey evaluates to.
+	Variants   []Variant              `json:"variants,omitempty"`
+	VariantKey expressions.Expression `json:"variantKey,omitempty"`
+}
+
+// Variant is a response clients are spread over by Weight, like an arm of
+// an A/B experiment.
+type Variant struct {
+	Name     string  `json:"name"`
+	Weight   int     `json:"weight"`
+	RespCode int     `json:"code"`
+	Reason   string  `json:"reason,omitempty"`
+	Content  Content `json:"content"`
+}
+
+func (variant *Variant) UnmarshalJSON(data []byte) error {
+	type Alias Variant
+	type Aux struct {
+		RespCode *int     `json:"code"`
+		Content  *Content `json:"content"`
+		*Alias
+	}
+	aux := &Aux{Alias: &Alias{Weight: 1}}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if aux.Name == "" {
+		return atBlock(data, errors.New("variants must have a name"))
+	}
+	if aux.Weight < 1 {
+		return atBlock(data, errors.New("variant weight must be at least 1"))
+	}
+	*variant = Variant(*aux.Alias)
+	if aux.Content != nil {
+		variant.Content = *aux.Content
+	}
+	// Like mappings, variants without content answer with no body.
+	switch {
+	case aux.RespCode != nil:
+		variant.RespCode = *aux.RespCode
+	case aux.Content == nil:
+		variant.RespCode = http.StatusNoContent
+	default:
+		variant.RespCode = http.StatusOK
+	}
+	if err := validateStatus(variant.RespCode, variant.Reason); err != nil {
+		return atBlock(data, err)
+	}
+	if variant.Reason != "" && variant.Content.Type == ContentTypeRaw {
+		return atBlock(data, errors.New("reason cannot be used with RAW content, which writes its own status line"))
+	}
+	return nil
+}
+
+// Quota is Limit requests, renewed every Window milliseconds or, without
+// one, only through the admin API. Once used up requests are answered with
+// Code and Content. Mappings with the same Name share their quota.
+type Quota struct {
+	Name    string  `json:"name,omitempty"`
+	Limit   int     `json:"limit"`
+	Window  int     `json:"window,omitempty"`
+	Code    int     `json:"code"`
+	Content Content `json:"content"`
+}
+
+func (quota *Quota) UnmarshalJSON(data []byte) error {
+	type Alias Quota
+	aux := &Alias{
+		Code:    http.StatusTooManyRequests,
+		Content: Content{Type: ContentTypeJson, Data: map[string]any{"error": "quota exceeded"}},
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if aux.Limit < 0 || aux.Window < 0 {
+		return atBlock(data, errors.New("quota limit and window cannot be negative"))
+	}
+	if err := validateStatus(aux.Code, ""); err != nil {
+		return atBlock(data, err)
+	}
+	*quota = Quota(*aux)
+	return nil
+}
+
+// Interim is a 1xx informational response, sent after waiting Delay
+// milliseconds. Its headers are kept for the final response.
+type Interim struct {
+	Code    int               `json:"code"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Delay   int               `json:"delay,omitempty"`
+}
+
+func (interim *Interim) UnmarshalJSON(data []byte) error {
+	type Alias Interim
+	aux := &Alias{Code: http.StatusEarlyHints}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if aux.Code < 100 || aux.Code > 199 || aux.Code == http.StatusSwitchingProtocols {
+		return atBlock(data, fmt.Errorf("interim code %d must be a 1xx status other than 101", aux.Code))
+	}
+	*interim = Interim(*aux)
+	return nil
+}
+
+// Publish is an event emitted by a mapping, e.g. the message a real
+// service would send to a broker after handling the request.
+type Publish struct {
+	Topic     string     `json:"topic"`
+	Content   Content    `json:"content"`
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+// Signature is an HMAC of a body sent in Header, with Prefix prepended,
+// e.g. "sha256=" for GitHub style webhooks.
+type Signature struct {
+	Header    string `json:"header"`
+	Algorithm string `json:"algorithm"`
+	Secret    string `json:"secret"`
+	Encoding  string `json:"encoding,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+}
+
+func (signature *Signature) UnmarshalJSON(data []byte) error {
+	type Alias Signature
+	aux := &Alias{Algorithm: "sha256"}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if aux.Header == "" || aux.Secret == "" {
+		return atBlock(data, errors.New("signature requires a header and a secret"))
+	}
+	if err := signing.Check(aux.Algorithm, aux.Encoding); err != nil {
+		return atBlock(data, err)
+	}
+	*signature = Signature(*aux)
+	return nil
+}
+
+// Sign returns the header value for body.
+func (signature *Signature) Sign(body []byte) string {
+	value, _ := signing.HMAC(signature.Algorithm, signature.Secret, body, signature.Encoding)
+	return signature.Prefix + value
+}
+
+func (publish *Publish) UnmarshalJSON(data []byte) error {
+	type Alias Publish
+	aux := (*Alias)(publish)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	if publish.Topic == "" {
+		return atBlock(data, errors.New("publish requires a topic"))
+	}
+	if publish.Content.Type != ContentTypeJson {
+		return atBlock(data, errors.New("publish only supports JSON content"))
+	}
+	return nil
+}
+
+// MarshalJSON leaves out the content of mappings answering without a body.
+func (mapping Mapping) MarshalJSON() ([]byte, error) {
+	type Alias Mapping
+	aux := struct {
+		Alias
+		Content *Content `json:"content,omitempty"`
+	}{Alias: Alias(mapping)}
+
+	if mapping.Content.Type != ContentTypeJson || mapping.Content.Data != nil || len(mapping.Content.Languages) > 0 {
+		aux.Content = &mapping.Content
+	}
+	return json.Marshal(aux)
+}
+
+// WalkContents calls fn with every content the mapping may answer with:
+// its own, those of its variants and the one of its exhausted quota, along
+// with the contents they pick by Accept-Language. Changes fn makes to a
+// content are kept.
+func (mapping *Mapping) WalkContents(fn func(content *Content) error) error {
+	contents := []*Content{&mapping.Content}
+	for i := range mapping.Variants {
+		contents = append(contents, &mapping.Variants[i].Content)
+	}
+	if mapping.Quota != nil {
+		contents = append(contents, &mapping.Quota.Content)
+	}
+	for _, content := range contents {
+		if err := walkContent(content, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkContent(content *Content, fn func(content *Content) error) error {
+	if err := fn(content); err != nil {
+		return err
+	}
+	for language, variant := range content.Languages {
+		if err := walkContent(&variant, fn); err != nil {
+			return err
+		}
+		content.Languages[language] = variant
+	}
+	return nil
 }
 
 func (mapping *Mapping) UnmarshalJSON(data []byte) error {
 	type Alias Mapping
 	type Aux struct {
-		Params   []json.RawMessage `json:"params"`
-		RespCode *int              `json:"code"`
-		Content  *Content          `json:"content"`
+		Params     []json.RawMessage          `json:"params"`
+		RespCode   *int                       `json:"code"`
+		Content    *Content                   `json:"content"`
+		Values     map[string]json.RawMessage `json:"values"`
+		VariantKey json.RawMessage            `json:"variantKey"`
 		*Alias
 	}
 	aux := &Aux{Alias: (*Alias)(mapping)}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
 	}
 
 	mapping.Params = make([]expressions.Expression, len(aux.Params))
 	for i, v := range aux.Params {
-		result, err := expressions.BuildExpression([]byte(v))
+		result, err := expressions.Parse([]byte(v))
 		if err != nil {
-			panic("error building param n: " + strconv.Itoa(i))
+			return inBlock(data, v, fmt.Errorf("error building param %d: %w", i, err))
+		}
+		if result.ReturnType() != reflect.Bool {
+			return inBlock(data, v, fmt.Errorf("error building param %d: expression evaluates to %s, not %s", i, result.ReturnType(), reflect.Bool))
 		}
 
 		mapping.Params[i] = result
 	}
 
+	mapping.Values = make(map[string]expressions.Expression, len(aux.Values))
+	for name, v := range aux.Values {
+		result, err := expressions.Parse([]byte(v))
+		if err != nil {
+			return inBlock(data, v, fmt.Errorf("error building value %s: %w", name, err))
+		}
+		mapping.Values[name] = result
+	}
+
+	if aux.Content != nil {
+		mapping.Content = *aux.Content
+	}
+	if aux.VariantKey != nil {
+		key, err := expressions.Parse(aux.VariantKey)
+		if err != nil {
+			return inBlock(data, aux.VariantKey, fmt.Errorf("error building variantKey: %w", err))
+		}
+		if key.ReturnType() != reflect.String {
+			return inBlock(data, aux.VariantKey, errors.New("variantKey must evaluate to a string"))
+		}
+		mapping.VariantKey = key
+	}
+	names := map[string]bool{}
+	for _, variant := range mapping.Variants {
+		if names[variant.Name] {
+			return atBlock(data, fmt.Errorf("duplicate variant %s", variant.Name))
+		}
+		names[variant.Name] = true
+	}
+	if mapping.ActiveFrom != nil && mapping.ActiveUntil != nil &&
+		mapping.ActiveFrom.At.IsZero() == mapping.ActiveUntil.At.IsZero() &&
+		!mapping.ActiveFrom.Resolve(time.Time{}).Before(mapping.ActiveUntil.Resolve(time.Time{})) {
+		return atBlock(data, errors.New("activeFrom must come before activeUntil"))
+	}
+	if mapping.TransferEncoding != "" && mapping.TransferEncoding != "chunked" && mapping.TransferEncoding != "identity" {
+		return atBlock(data, errors.New("transferEncoding must be chunked or identity, got "+mapping.TransferEncoding))
+	}
+	if mapping.ContentLength != nil && mapping.TransferEncoding != "" {
+		return atBlock(data, errors.New("contentLength and transferEncoding cannot be used together"))
+	}
+	if mapping.Signature != nil && mapping.Content.Type != ContentTypeJson && mapping.Content.Type != ContentTypeMsgpack {
+		return atBlock(data, errors.New("signature only supports JSON and MSGPACK content"))
+	}
 	if aux.RespCode == nil {
 		if aux.Content == nil {
 			mapping.RespCode = 204
 		} else {
 			mapping.RespCode = 200
-			mapping.Content = *aux.Content
-
 		}
 	} else {
 		mapping.RespCode = *aux.RespCode
-		mapping.Content = *aux.Content
+	}
+	if err := validateStatus(mapping.RespCode, mapping.Reason); err != nil {
+		return atBlock(data, err)
+	}
+	if mapping.Reason != "" {
+		if len(mapping.Trailers) > 0 || mapping.TransferEncoding != "" || mapping.Bandwidth > 0 {
+			return atBlock(data, errors.New("reason cannot be used with trailers, transferEncoding or bandwidth, as the response is sent in one go"))
+		}
+		if mapping.Content.Type == ContentTypeRaw {
+			return atBlock(data, errors.New("reason cannot be used with RAW content, which writes its own status line"))
+		}
 	}
 
 	return nil
@@ -143,20 +629,143 @@ type ContentType int
 const (
 	ContentTypeJson ContentType = iota
 	ContentTypeFile
+	ContentTypePaginate
+	ContentTypeGraphQL
+	ContentTypeMsgpack
+	ContentTypeUpload
+	ContentTypeRaw
+	ContentTypeExec
+	ContentTypeProxy
+	ContentTypeQuery
 )
 
 var stringToContentType = map[string]ContentType{
-	"JSON": ContentTypeJson,
-	"FILE": ContentTypeFile,
+	"JSON":     ContentTypeJson,
+	"FILE":     ContentTypeFile,
+	"PAGINATE": ContentTypePaginate,
+	"GRAPHQL":  ContentTypeGraphQL,
+	"MSGPACK":  ContentTypeMsgpack,
+	"UPLOAD":   ContentTypeUpload,
+	"RAW":      ContentTypeRaw,
+	"EXEC":     ContentTypeExec,
+	"PROXY":    ContentTypeProxy,
+	"QUERY":    ContentTypeQuery,
 }
 
 type Content struct {
 	Type ContentType `json:"type"`
 	Data any         `json:"data"`
+	// Template renders every string of Data as a Go template on each request.
+	Template         bool                 `json:"template,omitempty"`
+	CompiledTemplate *templating.Template `json:"-"`
+	// Languages holds variants picked by the request's Accept-Language.
+	Languages map[string]Content `json:"languages,omitempty"`
 }
 
 type DataFile struct {
 	Path string `json:"path"`
+	// RangeDelay is waited, in milliseconds, before answering a Range request.
+	RangeDelay int `json:"rangeDelay,omitempty"`
+	// AbortAfter drops the connection once that many body bytes were sent.
+	AbortAfter int64 `json:"abortAfter,omitempty"`
+	// SHA256 is the hex checksum the file must have at startup.
+	SHA256 string `json:"sha256,omitempty"`
+	// Encoding "gzip" tells the file is stored compressed, to be sent with
+	// a Content-Encoding or decompressed depending on the client.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+const EncodingGzip = "gzip"
+
+// DataPage is a dataset answered one page at a time, with the page and
+// size taken from query params.
+type DataPage struct {
+	// Items holds the dataset inline, otherwise it is read from File, a
+	// JSON array relative to the file declaring it, or taken from the
+	// server's Dataset of that name.
+	Items       []any  `json:"items,omitempty"`
+	File        string `json:"file,omitempty"`
+	Dataset     string `json:"dataset,omitempty"`
+	PageParam   string `json:"pageParam,omitempty"`
+	SizeParam   string `json:"sizeParam,omitempty"`
+	DefaultSize int    `json:"defaultSize,omitempty"`
+	MaxSize     int    `json:"maxSize,omitempty"`
+	// Filters maps query params to item fields, dotted for nested ones,
+	// keeping only the items whose field equals one of the given values.
+	Filters map[string]string `json:"filters,omitempty"`
+}
+
+// DataGraphQL answers GraphQL operations with values made up from an SDL
+// schema, relative to the file declaring it. It is compiled once the paths
+// of the configuration are resolved.
+type DataGraphQL struct {
+	Schema     string          `json:"schema"`
+	ListLength int             `json:"listLength,omitempty"`
+	Compiled   *graphql.Schema `json:"-"`
+}
+
+// DataUpload runs a resumable upload: POST starts a session answered with
+// its Location, PUT sends its chunks with Content-Range.
+type DataUpload struct {
+	// IDParam is the path param holding the session id on chunk requests.
+	IDParam string `json:"idParam,omitempty"`
+}
+
+// DataExec answers with the standard output of a command, which gets the
+// request as JSON on its standard input.
+type DataExec struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Timeout is how long, in milliseconds, the command may run.
+	Timeout     int    `json:"timeout,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// DataProxy forwards requests to an upstream server, rewriting parts of
+// its responses.
+type DataProxy struct {
+	URL     string        `json:"url"`
+	Target  *url.URL      `json:"-"`
+	Rewrite *ProxyRewrite `json:"rewrite,omitempty"`
+}
+
+// ProxyRewrite forces the status, headers or JSON fields of upstream
+// responses.
+type ProxyRewrite struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fields maps dotted paths of the JSON body to the values they get.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+func (content Content) MarshalJSON() ([]byte, error) {
+	type Alias Content
+	aux := struct {
+		Type string `json:"type,omitempty"`
+		Alias
+	}{Alias: Alias(content)}
+
+	switch content.Type {
+	case ContentTypeFile:
+		aux.Type = "FILE"
+	case ContentTypePaginate:
+		aux.Type = "PAGINATE"
+	case ContentTypeGraphQL:
+		aux.Type = "GRAPHQL"
+	case ContentTypeMsgpack:
+		aux.Type = "MSGPACK"
+	case ContentTypeUpload:
+		aux.Type = "UPLOAD"
+	case ContentTypeRaw:
+		aux.Type = "RAW"
+	case ContentTypeExec:
+		aux.Type = "EXEC"
+	case ContentTypeProxy:
+		aux.Type = "PROXY"
+	case ContentTypeQuery:
+		aux.Type = "QUERY"
+	}
+	return json.Marshal(aux)
 }
 
 func (content *Content) UnmarshalJSON(data []byte) error {
@@ -168,8 +777,8 @@ func (content *Content) UnmarshalJSON(data []byte) error {
 	}
 	aux := &Aux{Alias: (*Alias)(content)}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
 	}
 
 	if aux.Type == nil {
@@ -177,25 +786,131 @@ func (content *Content) UnmarshalJSON(data []byte) error {
 		var err error
 		content.Data, err = parseJsonData(aux.Data)
 		if err != nil {
-			return err
+			return atBlock(data, err)
 		}
 	} else {
 		switch stringToContentType[*aux.Type] {
-		case ContentTypeJson:
-			content.Type = ContentTypeJson
+		case ContentTypeJson, ContentTypeMsgpack:
+			content.Type = stringToContentType[*aux.Type]
 			var err error
 			content.Data, err = parseJsonData(aux.Data)
 			if err != nil {
-				return err
+				return atBlock(data, err)
 			}
 		case ContentTypeFile:
 			content.Type = ContentTypeFile
+			if aux.Data == nil {
+				return atBlock(data, errors.New("FILE content requires data with a path"))
+			}
 			var fileData DataFile
 			if err := json.Unmarshal(*aux.Data, &fileData); err != nil {
-				return err
+				return atBlock(data, err)
+			}
+			if sum, err := hex.DecodeString(fileData.SHA256); err != nil || fileData.SHA256 != "" && len(sum) != sha256.Size {
+				return atBlock(data, errors.New("FILE sha256 must be 64 hexadecimal digits"))
+			}
+			if fileData.Encoding != "" && fileData.Encoding != EncodingGzip {
+				return atBlock(data, errors.New("FILE encoding must be gzip, got "+fileData.Encoding))
 			}
 			content.Data = fileData
+		case ContentTypePaginate:
+			content.Type = ContentTypePaginate
+			if aux.Data == nil {
+				return atBlock(data, errors.New("PAGINATE content requires data with items or a file"))
+			}
+			page := DataPage{PageParam: "page", SizeParam: "size", DefaultSize: 10}
+			if err := json.Unmarshal(*aux.Data, &page); err != nil {
+				return atBlock(data, err)
+			}
+			if page.Items == nil && page.File == "" && page.Dataset == "" {
+				return atBlock(data, errors.New("PAGINATE content requires items, a file or a dataset"))
+			}
+			if page.DefaultSize <= 0 {
+				return atBlock(data, errors.New("PAGINATE defaultSize must be positive"))
+			}
+			content.Data = page
+		case ContentTypeGraphQL:
+			content.Type = ContentTypeGraphQL
+			if aux.Data == nil {
+				return atBlock(data, errors.New("GRAPHQL content requires data with a schema"))
+			}
+			schema := DataGraphQL{ListLength: 2}
+			if err := json.Unmarshal(*aux.Data, &schema); err != nil {
+				return atBlock(data, err)
+			}
+			content.Data = schema
+		case ContentTypeUpload:
+			content.Type = ContentTypeUpload
+			upload := DataUpload{IDParam: "id"}
+			if aux.Data != nil {
+				if err := json.Unmarshal(*aux.Data, &upload); err != nil {
+					return atBlock(data, err)
+				}
+			}
+			content.Data = upload
+		case ContentTypeRaw:
+			content.Type = ContentTypeRaw
+			if aux.Data == nil {
+				return atBlock(data, errors.New("RAW content requires data with text, hex or base64"))
+			}
+			var payload Payload
+			if err := json.Unmarshal(*aux.Data, &payload); err != nil {
+				return atBlock(data, err)
+			}
+			content.Data = payload
+		case ContentTypeExec:
+			content.Type = ContentTypeExec
+			if aux.Data == nil {
+				return atBlock(data, errors.New("EXEC content requires data with a command"))
+			}
+			command := DataExec{Timeout: 5000, ContentType: "application/json"}
+			if err := json.Unmarshal(*aux.Data, &command); err != nil {
+				return atBlock(data, err)
+			}
+			if command.Command == "" {
+				return atBlock(data, errors.New("EXEC content requires a command"))
+			}
+			if command.Timeout <= 0 {
+				return atBlock(data, errors.New("EXEC timeout must be positive"))
+			}
+			content.Data = command
+		case ContentTypeProxy:
+			content.Type = ContentTypeProxy
+			if aux.Data == nil {
+				return atBlock(data, errors.New("PROXY content requires data with a url"))
+			}
+			var proxy DataProxy
+			if err := json.Unmarshal(*aux.Data, &proxy); err != nil {
+				return atBlock(data, err)
+			}
+			target, err := url.Parse(proxy.URL)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+				return atBlock(data, errors.New("PROXY url must be an absolute http or https URL, got "+proxy.URL))
+			}
+			proxy.Target = target
+			content.Data = proxy
+		case ContentTypeQuery:
+			content.Type = ContentTypeQuery
+			if aux.Data == nil {
+				return atBlock(data, errors.New("QUERY content requires data with a dataset"))
+			}
+			var query DataQuery
+			if err := json.Unmarshal(*aux.Data, &query); err != nil {
+				return atBlock(data, err)
+			}
+			if query.Dataset == "" {
+				return atBlock(data, errors.New("QUERY content requires a dataset"))
+			}
+			content.Data = query
+		}
+	}
+
+	if content.Template && (content.Type == ContentTypeJson || content.Type == ContentTypeMsgpack) {
+		compiled, err := templating.Compile(content.Data)
+		if err != nil {
+			return atBlock(data, err)
 		}
+		content.CompiledTemplate = compiled
 	}
 
 	return nil
@@ -215,26 +930,129 @@ func parseJsonData(data *json.RawMessage) (any, error) {
 }
 
 func ParseConfiguration(filePath string) (*Servers, error) {
+	return ParseConfigurationWithOptions(filePath, Options{})
+}
+
+// Options change how a configuration file is parsed.
+type Options struct {
+	// Variables give some of the variables of the file other values.
+	Variables map[string]string
+	// FixturesDir is where the FILE, PAGINATE and GRAPHQL files of every
+	// file are resolved instead of the directory of the file declaring them.
+	FixturesDir string
+}
+
+// ParseConfigurationWithOptions parses a configuration file as options say.
+func ParseConfigurationWithOptions(filePath string, options Options) (*Servers, error) {
 	file, err := readFile(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return parseData(file, filePath, options)
+}
 
-	var value Servers
-	err = json.Unmarshal(file, &value)
+// ParseData parses a configuration already in memory. filePath is used in
+// error messages, to tell YAML from JSON by its extension and to resolve
+// includes and other relative paths.
+func ParseData(file []byte, filePath string) (*Servers, error) {
+	return parseData(file, filePath, Options{})
+}
+
+func parseData(file []byte, filePath string, options Options) (*Servers, error) {
+	src, err := newSource(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := loadVariables(src, options.Variables)
+	if err != nil {
+		return nil, err
+	}
+	fragments, err := loadFragments(src)
 	if err != nil {
-		var fallback Configuration
-		err = json.Unmarshal(file, &fallback)
+		return nil, err
+	}
+	defs := definitions{variables: variables, fragments: fragments}
+	if err := src.substitute(defs); err != nil {
+		return nil, err
+	}
+
+	var value Servers
+	if isMultiServer(src.data) {
+		if err := json.Unmarshal(src.data, &value); err != nil {
+			return nil, src.locate(err)
+		}
+	} else {
+		var single Configuration
+		if err := json.Unmarshal(src.data, &single); err != nil {
+			return nil, src.locate(err)
+		}
+		value = Servers{Configurations: []Configuration{single}}
+	}
+	if root, err := filepath.Abs(filePath); err == nil {
+		value.Files = []string{root}
+	}
+
+	for i := range value.Configurations {
+		if value.Configurations[i].Name == "" {
+			value.Configurations[i].Name = "server" + strconv.Itoa(i)
+		}
+		if err := resolveServerPaths(&value.Configurations[i], filePath, options.FixturesDir); err != nil {
+			return nil, err
+		}
+		included, err := resolveIncludes(&value.Configurations[i], filePath, defs, options.FixturesDir)
 		if err != nil {
 			return nil, err
 		}
-
-		return &Servers{Configurations: []Configuration{fallback}}, nil
+		for _, file := range included {
+			if !slices.Contains(value.Files, file) {
+				value.Files = append(value.Files, file)
+			}
+		}
+		if err := resolveScenarios(&value.Configurations[i], filePath, defs, options.FixturesDir); err != nil {
+			return nil, err
+		}
+		if err := loadDatasets(&value.Configurations[i], filePath); err != nil {
+			return nil, err
+		}
+		if err := verifyChecksums(&value.Configurations[i]); err != nil {
+			return nil, err
+		}
+		if err := validateIdentifiers(&value.Configurations[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateStandbys(value.Configurations); err != nil {
+		return nil, err
 	}
 
 	return &value, nil
 }
 
+// isMultiServer tells whether the top level object holds a "servers" list
+// or is a single server configuration, without decoding the whole file.
+func isMultiServer(file []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(file))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if key == "servers" {
+			return true
+		}
+
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
 func readFile(file string) ([]byte, error) {
 	fileBytes, err := os.ReadFile(file)
 	if err != nil {
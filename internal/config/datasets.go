@@ -0,0 +1,173 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+)
+
+// Dataset is a table of records loaded at startup, from a CSV file whose
+// first line names the fields or from a JSON array of objects.
+type Dataset struct {
+	File    string           `json:"-"`
+	Records []map[string]any `json:"-"`
+}
+
+func (dataset Dataset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dataset.File)
+}
+
+func (dataset *Dataset) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &dataset.File); err != nil {
+		return atBlock(data, errors.New("a dataset is the path of a CSV or JSON file"))
+	}
+	return nil
+}
+
+// loadDatasets reads the datasets of a server, relative to the file
+// declaring them, and seeds the PAGINATE and QUERY contents using them.
+func loadDatasets(configuration *Configuration, filePath string) error {
+	for name, dataset := range configuration.Datasets {
+		dataset.File = resolvePath(filepath.Dir(filePath), dataset.File)
+		var err error
+		if dataset.Records, err = readDataset(dataset.File); err != nil {
+			return fmt.Errorf("error loading dataset %s: %w", name, err)
+		}
+	}
+
+	for _, endpoint := range configuration.Endpoints {
+		for i := range endpoint.Mappings {
+			mapping := &endpoint.Mappings[i]
+			if err := seedContent(&mapping.Content, configuration.Datasets); err != nil {
+				return fmt.Errorf("%s %s: %w", endpoint.Verb, endpoint.Path, err)
+			}
+			for language, variant := range mapping.Content.Languages {
+				if err := seedContent(&variant, configuration.Datasets); err != nil {
+					return fmt.Errorf("%s %s: %w", endpoint.Verb, endpoint.Path, err)
+				}
+				mapping.Content.Languages[language] = variant
+			}
+		}
+	}
+	return nil
+}
+
+func seedContent(content *Content, datasets map[string]*Dataset) error {
+	switch data := content.Data.(type) {
+	case DataPage:
+		if data.Dataset == "" {
+			return nil
+		}
+		dataset, ok := datasets[data.Dataset]
+		if !ok {
+			return errors.New("unknown dataset " + data.Dataset)
+		}
+		data.Items = make([]any, len(dataset.Records))
+		for i, record := range dataset.Records {
+			data.Items[i] = record
+		}
+		content.Data = data
+	case DataQuery:
+		dataset, ok := datasets[data.Dataset]
+		if !ok {
+			return errors.New("unknown dataset " + data.Dataset)
+		}
+		data.Records = dataset.Records
+		content.Data = data
+	}
+	return nil
+}
+
+func readDataset(path string) ([]map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		var records []map[string]any
+		if err := json.NewDecoder(file).Decode(&records); err != nil {
+			return nil, fmt.Errorf("expected a JSON array of objects: %w", err)
+		}
+		return records, nil
+	}
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("expected a header line naming the fields")
+	}
+	records := make([]map[string]any, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]any, len(row))
+		for i, field := range rows[0] {
+			record[field] = row[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// DataQuery answers with the records of a dataset meeting every condition,
+// sorted, limited and reduced to some fields.
+type DataQuery struct {
+	Dataset string      `json:"dataset"`
+	Where   []Condition `json:"where,omitempty"`
+	Select  []string    `json:"select,omitempty"`
+	// Sort orders the records by a field, descending when prefixed with a
+	// "-". SortParam names a query param overriding it.
+	Sort      string `json:"sort,omitempty"`
+	SortParam string `json:"sortParam,omitempty"`
+	// Limit caps the records answered. LimitParam names a query param
+	// asking for fewer.
+	Limit      int              `json:"limit,omitempty"`
+	LimitParam string           `json:"limitParam,omitempty"`
+	Records    []map[string]any `json:"-"`
+}
+
+// Condition compares a field of the records with the value of an
+// expression, usually read from the request. Conditions whose value is
+// empty are left out, so optional params do not filter anything.
+type Condition struct {
+	Field string                 `json:"field"`
+	Op    string                 `json:"op"`
+	Value expressions.Expression `json:"value"`
+}
+
+var conditionOps = []string{"eq", "ne", "lt", "lte", "gt", "gte", "contains"}
+
+func (condition *Condition) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Field string          `json:"field"`
+		Op    string          `json:"op"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return atBlock(data, err)
+	}
+	if aux.Field == "" || aux.Value == nil {
+		return atBlock(data, errors.New("conditions require a field and a value"))
+	}
+	if aux.Op == "" {
+		aux.Op = "eq"
+	}
+	if !slices.Contains(conditionOps, aux.Op) {
+		return atBlock(data, fmt.Errorf("condition op must be one of %s, got %s", strings.Join(conditionOps, ", "), aux.Op))
+	}
+	value, err := expressions.Parse(aux.Value)
+	if err != nil {
+		return inBlock(data, aux.Value, fmt.Errorf("error building condition value: %w", err))
+	}
+	*condition = Condition{Field: aux.Field, Op: aux.Op, Value: value}
+	return nil
+}
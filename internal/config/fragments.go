@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+const (
+	// fragmentsKey is the top level object declaring the fragments of a file.
+	fragmentsKey = "fragments"
+	// fragmentKey names the fragment an object stands for, its other keys
+	// being the parameters of the fragment.
+	fragmentKey = "$fragment"
+)
+
+// loadFragments reads the fragments declared by a config file.
+func loadFragments(src *source) (map[string]json.RawMessage, error) {
+	var declared struct {
+		Fragments map[string]json.RawMessage `json:"fragments"`
+	}
+	if err := json.Unmarshal(src.data, &declared); err != nil {
+		return nil, src.locate(err)
+	}
+	return declared.Fragments, nil
+}
+
+// object copies an object, or expands it when it is a fragment reference.
+// Objects are decoded whole to find out, then walked again.
+func (t *substitution) object(root bool, line, column int) error {
+	start := t.offset()
+	var raw json.RawMessage
+	if err := t.decoder.Decode(&raw); err != nil {
+		return t.src.locate(err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return t.src.locate(err)
+	}
+	if _, ok := fields[fragmentKey]; ok {
+		expanded, err := t.fragment(fields, sourcePoint{line: line, column: column})
+		if err != nil && t.at != nil {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%s:%d:%d: %w", t.src.path, line, column, err)
+		}
+		t.append(expanded)
+		return nil
+	}
+
+	child := t.child(t.src, start, raw, t.at)
+	if err := child.token(root, line, column); err != nil {
+		return err
+	}
+	t.append(child)
+	return nil
+}
+
+// fragment expands a reference. Parameters are expanded first, then given
+// to the fragment as variables, on top of the file's own.
+func (t *substitution) fragment(fields map[string]json.RawMessage, at sourcePoint) (*substitution, error) {
+	var name string
+	if err := json.Unmarshal(fields[fragmentKey], &name); err != nil {
+		return nil, fmt.Errorf("%s must be a fragment name", fragmentKey)
+	}
+	fragment, ok := t.defs.fragments[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined fragment %s", name)
+	}
+	if slices.Contains(t.expanding, name) {
+		return nil, fmt.Errorf("cycle through fragment %s", name)
+	}
+
+	variables := maps.Clone(t.defs.variables)
+	if variables == nil {
+		variables = map[string]json.RawMessage{}
+	}
+	for key, value := range fields {
+		if key == fragmentKey {
+			continue
+		}
+		param := t.child(&source{path: t.src.path, data: value}, 0, value, &at)
+		if err := param.value(false); err != nil {
+			return nil, err
+		}
+		variables[key] = param.buffer.Bytes()
+	}
+
+	body := t.child(&source{path: t.src.path, data: fragment}, 0, fragment, &at)
+	body.defs.variables = variables
+	body.expanding = append(slices.Clip(t.expanding), name)
+	if err := body.value(false); err != nil {
+		return nil, fmt.Errorf("fragment %s: %w", name, err)
+	}
+	return body, nil
+}
+
+// child walks data, found at offset base of src.
+func (t *substitution) child(src *source, base int, data []byte, at *sourcePoint) *substitution {
+	child := &substitution{src: src, decoder: json.NewDecoder(bytes.NewReader(data)), base: base, at: at, defs: t.defs, expand: t.expand, expanding: t.expanding}
+	child.decoder.UseNumber()
+	return child
+}
+
+// append copies what a child walked, along with its points.
+func (t *substitution) append(child *substitution) {
+	offset := t.buffer.Len()
+	for _, point := range child.points {
+		point.offset += offset
+		t.points = append(t.points, point)
+	}
+	t.buffer.Write(child.buffer.Bytes())
+}
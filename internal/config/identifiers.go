@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Label identifies the endpoint in logs and headers, falling back to its verb and path.
+func (endpoint Endpoint) Label() string {
+	if endpoint.ID != "" {
+		return endpoint.ID
+	}
+	return endpoint.Verb + " " + endpoint.Path
+}
+
+// Label identifies the mapping in logs and headers, falling back to its index.
+func (mapping Mapping) Label(index int) string {
+	if mapping.ID != "" {
+		return mapping.ID
+	}
+	return strconv.Itoa(index)
+}
+
+func validateIdentifiers(configuration *Configuration) error {
+	endpoints := map[string]bool{}
+	mappings := map[string]bool{}
+
+	for _, endpoint := range configuration.Endpoints {
+		if endpoint.ID != "" {
+			if endpoints[endpoint.ID] {
+				return fmt.Errorf("duplicate endpoint id %s in server %s", endpoint.ID, configuration.Name)
+			}
+			endpoints[endpoint.ID] = true
+		}
+
+		for _, mapping := range endpoint.Mappings {
+			if mapping.ID == "" {
+				continue
+			}
+			if mappings[mapping.ID] {
+				return fmt.Errorf("duplicate mapping id %s in server %s", mapping.ID, configuration.Name)
+			}
+			mappings[mapping.ID] = true
+		}
+	}
+
+	return nil
+}
+
+// validateStandbys checks that standby servers name another server, which
+// is not a standby itself.
+func validateStandbys(configurations []Configuration) error {
+	primaries := map[string]bool{}
+	for _, configuration := range configurations {
+		primaries[configuration.Name] = configuration.StandbyFor == ""
+	}
+	for _, configuration := range configurations {
+		if configuration.StandbyFor != "" && !primaries[configuration.StandbyFor] {
+			return fmt.Errorf("server %s is standby for %s, which is not a primary server", configuration.Name, configuration.StandbyFor)
+		}
+	}
+	return nil
+}
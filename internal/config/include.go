@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+type includeFile struct {
+	Endpoints []Endpoint `json:"endpoint"`
+	Includes  []string   `json:"include"`
+}
+
+// resolveIncludes appends the endpoints of the included files to the
+// configuration and returns the paths of those files.
+func resolveIncludes(configuration *Configuration, filePath string, defs definitions, fixturesDir string) ([]string, error) {
+	root, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	endpoints, err := loadIncludes(configuration.Includes, filepath.Dir(root), map[string]bool{root: true}, defs, fixturesDir, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	configuration.Endpoints = append(configuration.Endpoints, endpoints...)
+	configuration.Includes = nil
+	return files, nil
+}
+
+func loadIncludes(includes []string, baseDir string, visiting map[string]bool, defs definitions, fixturesDir string, files *[]string) ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	for _, include := range includes {
+		path, err := filepath.Abs(resolvePath(baseDir, include))
+		if err != nil {
+			return nil, err
+		}
+		if visiting[path] {
+			return nil, errors.New("include cycle detected at " + path)
+		}
+
+		src, err := readSource(path)
+		if err != nil {
+			return nil, err
+		}
+		*files = append(*files, path)
+		if err := src.substitute(defs); err != nil {
+			return nil, err
+		}
+
+		var value includeFile
+		if err := json.Unmarshal(src.data, &value); err != nil {
+			return nil, fmt.Errorf("error parsing include: %w", src.locate(err))
+		}
+		if err := resolveFixtures(value.Endpoints, fixturesBase(filepath.Dir(path), fixturesDir)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		visiting[path] = true
+		nested, err := loadIncludes(value.Includes, filepath.Dir(path), visiting, defs, fixturesDir, files)
+		delete(visiting, path)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints = append(endpoints, value.Endpoints...)
+		endpoints = append(endpoints, nested...)
+	}
+
+	return endpoints, nil
+}
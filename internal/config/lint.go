@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Issue is a problem found by Lint at a position of a config file.
+type Issue struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (issue Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", issue.File, issue.Line, issue.Column, issue.Message)
+}
+
+// commentKey can be used on any object to document the configuration.
+const commentKey = "$comment"
+
+// shape describes the keys allowed in a JSON value. A nil shape accepts
+// anything, like expressions or response data, and is only checked for
+// duplicate keys.
+type shape struct {
+	fields map[string]*shape
+	values *shape
+	items  *shape
+}
+
+var (
+	configurationShape = shapeOf(reflect.TypeOf(Configuration{}), map[reflect.Type]*shape{})
+	includeShape       = shapeOf(reflect.TypeOf(includeFile{}), map[reflect.Type]*shape{})
+)
+
+func init() {
+	// A file holds either a single server or a "servers" list of them.
+	configurationShape.fields["servers"] = &shape{items: configurationShape}
+	// Variables hold any JSON value, see variables.go.
+	configurationShape.fields[variablesKey] = nil
+	configurationShape.fields[fragmentsKey] = nil
+}
+
+// shapeOf derives the allowed keys from the json tags of the config types,
+// so the linter follows new fields without being told about them.
+func shapeOf(t reflect.Type, seen map[reflect.Type]*shape) *shape {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return shapeOf(t.Elem(), seen)
+	case reflect.Slice:
+		return &shape{items: shapeOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &shape{values: shapeOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if s, ok := seen[t]; ok {
+			return s
+		}
+		s := &shape{fields: map[string]*shape{}}
+		seen[t] = s
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "" && t.Field(i).Anonymous {
+				maps.Copy(s.fields, shapeOf(t.Field(i).Type, seen).fields)
+				continue
+			}
+			if name == "" || name == "-" {
+				continue
+			}
+			s.fields[name] = shapeOf(t.Field(i).Type, seen)
+		}
+		return s
+	}
+	return nil
+}
+
+// Lint reports unknown fields, duplicate keys and unreachable mappings in a
+// config file and the files it includes. The file is expected to be valid JSON.
+func Lint(filePath string) ([]Issue, error) {
+	return lintFile(filePath, configurationShape, map[string]bool{})
+}
+
+func lintFile(filePath string, root *shape, visited map[string]bool) ([]Issue, error) {
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if visited[path] {
+		return nil, nil
+	}
+	visited[path] = true
+
+	src, err := readSource(path)
+	if err != nil {
+		return nil, err
+	}
+	src.path = filePath
+	file := src.data
+
+	l := linter{src: src, decoder: json.NewDecoder(bytes.NewReader(file))}
+	if err := l.value(root); err != nil {
+		return nil, fmt.Errorf("error linting %s: %w", filePath, err)
+	}
+	l.unreachableMappings()
+	l.bodilessContent()
+
+	var includes struct {
+		Include []string `json:"include"`
+		Servers []struct {
+			Include []string `json:"include"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal(file, &includes); err != nil {
+		return nil, err
+	}
+	for _, server := range includes.Servers {
+		includes.Include = append(includes.Include, server.Include...)
+	}
+
+	issues := l.issues
+	for _, include := range includes.Include {
+		nested, err := lintFile(resolvePath(filepath.Dir(filePath), include), includeShape, visited)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, nested...)
+	}
+	return issues, nil
+}
+
+type linter struct {
+	src     *source
+	decoder *json.Decoder
+	issues  []Issue
+}
+
+func (l *linter) value(s *shape) error {
+	token, err := l.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		return l.object(s)
+	case json.Delim('['):
+		var items *shape
+		if s != nil {
+			items = s.items
+		}
+		for l.decoder.More() {
+			if err := l.value(items); err != nil {
+				return err
+			}
+		}
+		_, err := l.decoder.Token()
+		return err
+	}
+	return nil
+}
+
+func (l *linter) object(s *shape) error {
+	seen, mark := map[string]bool{}, len(l.issues)
+	for l.decoder.More() {
+		offset := l.nextOffset()
+		token, err := l.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		if seen[key] {
+			l.report(offset, fmt.Sprintf("duplicate key %q", key))
+		}
+		seen[key] = true
+
+		var child *shape
+		if s != nil {
+			if s.fields != nil {
+				var known bool
+				child, known = s.fields[key]
+				if !known && key != commentKey {
+					l.report(offset, unknownField(key, s.fields))
+				}
+			} else {
+				child = s.values
+			}
+		}
+
+		if err := l.value(child); err != nil {
+			return err
+		}
+	}
+	// Fragment references hold parameters rather than fields.
+	if seen[fragmentKey] {
+		l.issues = l.issues[:mark]
+	}
+	_, err := l.decoder.Token()
+	return err
+}
+
+// nextOffset skips the separators after the last token to find where the
+// next one starts.
+func (l *linter) nextOffset() int {
+	offset := int(l.decoder.InputOffset())
+	for offset < len(l.src.data) && strings.IndexByte(" \t\r\n,:", l.src.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (l *linter) report(offset int, message string) {
+	line, column := l.src.position(offset)
+	l.issues = append(l.issues, Issue{File: l.src.path, Line: line, Column: column, Message: message})
+}
+
+func position(data []byte, offset int) (int, int) {
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, column
+}
+
+func unknownField(key string, fields map[string]*shape) string {
+	suggestion, best := "", 3
+	for field := range fields {
+		d := distance(key, field)
+		if d*2 < len(key) && (d < best || d == best && field < suggestion) {
+			suggestion, best = field, d
+		}
+	}
+	if suggestion == "" {
+		return fmt.Sprintf("unknown field %q", key)
+	}
+	return fmt.Sprintf("unknown field %q, did you mean %q?", key, suggestion)
+}
+
+// distance is the Levenshtein distance between two keys.
+func distance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// OverridePort changes the port of the server with the given name.
+func (servers *Servers) OverridePort(name string, port int) error {
+	for i := range servers.Configurations {
+		if servers.Configurations[i].Name == name {
+			servers.Configurations[i].Port = port
+			return nil
+		}
+	}
+	return fmt.Errorf("no server named %s", name)
+}
+
+// Only keeps the servers whose names are listed, in their original order.
+func (servers *Servers) Only(names []string) error {
+	for _, name := range names {
+		found := slices.ContainsFunc(servers.Configurations, func(configuration Configuration) bool {
+			return configuration.Name == name
+		})
+		if !found {
+			return fmt.Errorf("no server named %s", name)
+		}
+	}
+
+	servers.Configurations = slices.DeleteFunc(servers.Configurations, func(configuration Configuration) bool {
+		return !slices.Contains(names, configuration.Name)
+	})
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/dsa-ferreira/doppelganger/internal/graphql"
+)
+
+func resolvePath(baseDir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// fixturesBase is the directory the fixtures of a file in dir are relative
+// to, unless overridden for every file.
+func fixturesBase(dir string, override string) string {
+	if override != "" {
+		return override
+	}
+	return dir
+}
+
+// resolveServerPaths makes the file references of a server relative to the
+// config file declaring it, or to fixturesDir for the fixtures of its
+// contents.
+func resolveServerPaths(configuration *Configuration, filePath string, fixturesDir string) error {
+	baseDir := filepath.Dir(filePath)
+
+	if configuration.TLS != nil {
+		configuration.TLS.CertFile = resolvePath(baseDir, configuration.TLS.CertFile)
+		configuration.TLS.KeyFile = resolvePath(baseDir, configuration.TLS.KeyFile)
+	}
+	return resolveFixtures(configuration.Endpoints, fixturesBase(baseDir, fixturesDir))
+}
+
+// resolveFixtures makes the files read by the contents of the endpoints
+// relative to baseDir and checks they exist, so a wrong path fails the
+// startup rather than the first request.
+func resolveFixtures(endpoints []Endpoint, baseDir string) error {
+	for _, endpoint := range endpoints {
+		for i := range endpoint.Mappings {
+			if err := resolveMappingFixtures(&endpoint.Mappings[i], baseDir); err != nil {
+				return fmt.Errorf("%s %s: %w", endpoint.Verb, endpoint.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func resolveMappingFixtures(mapping *Mapping, baseDir string) error {
+	return mapping.WalkContents(func(content *Content) error {
+		return resolveContentFixtures(content, baseDir)
+	})
+}
+
+func resolveContentFixtures(content *Content, baseDir string) error {
+	switch data := content.Data.(type) {
+	case DataFile:
+		data.Path = resolvePath(baseDir, data.Path)
+		if err := fixtureExists("FILE", data.Path); err != nil {
+			return err
+		}
+		content.Data = data
+	case DataPage:
+		if data.File == "" {
+			break
+		}
+		data.File = resolvePath(baseDir, data.File)
+		if err := fixtureExists("PAGINATE file", data.File); err != nil {
+			return err
+		}
+		content.Data = data
+	case DataGraphQL:
+		data.Schema = resolvePath(baseDir, data.Schema)
+		if err := fixtureExists("GRAPHQL schema", data.Schema); err != nil {
+			return err
+		}
+		var err error
+		if data.Compiled, err = graphql.Load(data.Schema, data.ListLength); err != nil {
+			return fmt.Errorf("error loading GraphQL schema: %w", err)
+		}
+		content.Data = data
+	}
+	return nil
+}
+
+func fixtureExists(what string, path string) error {
+	_, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s %s does not exist", what, path)
+	}
+	return err
+}
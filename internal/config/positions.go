@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// blockError ties a parse error to the raw JSON block it was found in, so it
+// can be reported with a line and column.
+type blockError struct {
+	block  []byte
+	offset int
+	err    error
+}
+
+func (e *blockError) Error() string {
+	return e.err.Error()
+}
+
+func (e *blockError) Unwrap() error {
+	return e.err
+}
+
+// atBlock attaches block to err, unless an inner block was already attached.
+func atBlock(block []byte, err error) error {
+	var located *blockError
+	if err == nil || errors.As(err, &located) {
+		return err
+	}
+	return &blockError{block: block, err: err}
+}
+
+// inBlock attaches err to the part of block holding inner.
+func inBlock(block []byte, inner []byte, err error) error {
+	return &blockError{block: block, offset: max(bytes.Index(block, inner), 0), err: err}
+}
+
+// locate prefixes err with the file, line and column it points at, when known.
+func (s *source) locate(err error) error {
+	file := s.data
+	offset := -1
+	var syntax *json.SyntaxError
+	var located *blockError
+	switch {
+	case errors.As(err, &syntax):
+		offset = int(syntax.Offset)
+	case errors.As(err, &located):
+		if start := blockOffset(file, located.block); start >= 0 {
+			offset = start + located.offset
+		}
+	}
+
+	if offset < 0 {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	line, column := s.position(min(offset, len(file)))
+	return fmt.Errorf("%s:%d:%d: %w", s.path, line, column, err)
+}
+
+// blockOffset finds where block starts in file. Blocks handed to
+// UnmarshalJSON share the decoded file's memory, which pins down repeated
+// blocks; copies are searched for instead.
+func blockOffset(file []byte, block []byte) int {
+	if len(block) == 0 {
+		return -1
+	}
+	start := cap(file) - cap(block)
+	if start >= 0 && start+len(block) <= len(file) && &file[start] == &block[0] {
+		return start
+	}
+	return bytes.Index(file, block)
+}
@@ -0,0 +1,44 @@
+package config
+
+import "slices"
+
+// ApplyProfiles drops every endpoint and mapping tagged with profiles that
+// are not active. Untagged endpoints and mappings are always kept.
+func (servers *Servers) ApplyProfiles(active []string) {
+	for i := range servers.Configurations {
+		configuration := &servers.Configurations[i]
+
+		endpoints := make([]Endpoint, 0, len(configuration.Endpoints))
+		for _, endpoint := range configuration.Endpoints {
+			if !profileActive(endpoint.Profiles, active) {
+				continue
+			}
+
+			mappings := make([]Mapping, 0, len(endpoint.Mappings))
+			for _, mapping := range endpoint.Mappings {
+				if profileActive(mapping.Profiles, active) {
+					mappings = append(mappings, mapping)
+				}
+			}
+			if len(endpoint.Mappings) > 0 && len(mappings) == 0 {
+				continue
+			}
+
+			endpoint.Mappings = mappings
+			endpoints = append(endpoints, endpoint)
+		}
+		configuration.Endpoints = endpoints
+	}
+}
+
+func profileActive(tags []string, active []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(active, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// lintMapping holds what decides whether a mapping answers, as written.
+type lintMapping struct {
+	ID            string            `json:"id"`
+	Params        []json.RawMessage `json:"params"`
+	Profiles      []string          `json:"profiles"`
+	Scenario      string            `json:"scenario"`
+	RequiredState string            `json:"requiredState"`
+	ActiveFrom    json.RawMessage   `json:"activeFrom"`
+	ActiveUntil   json.RawMessage   `json:"activeUntil"`
+}
+
+// gated tells whether the mapping may be left out or skipped whatever the
+// request, so it cannot hide the mappings after it.
+func (mapping lintMapping) gated() bool {
+	return len(mapping.Profiles) > 0 || mapping.Scenario != "" && mapping.RequiredState != "" ||
+		mapping.ActiveFrom != nil || mapping.ActiveUntil != nil
+}
+
+// unreachableMappings reports mappings that can never answer because an
+// earlier mapping of the same endpoint requires a subset of their params,
+// such as a catch-all placed first. Params are compared as written, so
+// equivalent but differently written ones are not caught.
+func (l *linter) unreachableMappings() {
+endpoints:
+	for _, endpoint := range l.endpoints() {
+		var earlier []lintMapping
+		var earlierParams [][]string
+		for i, raw := range endpoint.Mappings {
+			var mapping lintMapping
+			var keys map[string]json.RawMessage
+			if json.Unmarshal(raw, &mapping) != nil || json.Unmarshal(raw, &keys) != nil {
+				continue endpoints
+			}
+			// What a fragment expands to is not known here.
+			if _, ok := keys[fragmentKey]; ok {
+				continue endpoints
+			}
+			params := canonicalParams(mapping.Params)
+			for j, previous := range earlier {
+				if previous.gated() || !subset(earlierParams[j], params) {
+					continue
+				}
+				label := Mapping{ID: previous.ID}.Label(j)
+				message := fmt.Sprintf("mapping %s can never match, mapping %s before it requires only some of its params", Mapping{ID: mapping.ID}.Label(i), label)
+				if len(earlierParams[j]) == 0 {
+					message = fmt.Sprintf("mapping %s can never match, mapping %s before it has no params and matches every request", Mapping{ID: mapping.ID}.Label(i), label)
+				}
+				l.report(max(blockOffset(l.src.data, raw), 0), message)
+				break
+			}
+			earlier = append(earlier, mapping)
+			earlierParams = append(earlierParams, params)
+		}
+	}
+}
+
+// lintEndpoint holds the mappings of an endpoint, as written.
+type lintEndpoint struct {
+	Mappings []json.RawMessage `json:"mappings"`
+}
+
+// endpoints reads the endpoints of every server of the file.
+func (l *linter) endpoints() []lintEndpoint {
+	var file struct {
+		Endpoints []lintEndpoint `json:"endpoint"`
+		Servers   []struct {
+			Endpoints []lintEndpoint `json:"endpoint"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal(l.src.data, &file); err != nil {
+		return nil
+	}
+	for _, server := range file.Servers {
+		file.Endpoints = append(file.Endpoints, server.Endpoints...)
+	}
+	return file.Endpoints
+}
+
+// canonicalParams re-encodes params so key order and spacing do not count.
+func canonicalParams(params []json.RawMessage) []string {
+	canonical := make([]string, 0, len(params))
+	for _, param := range params {
+		var value any
+		if err := json.Unmarshal(param, &value); err != nil {
+			canonical = append(canonical, string(param))
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		canonical = append(canonical, string(encoded))
+	}
+	return canonical
+}
+
+func subset(params []string, of []string) bool {
+	for _, param := range params {
+		if !slices.Contains(of, param) {
+			return false
+		}
+	}
+	return true
+}
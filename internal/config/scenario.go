@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dsa-ferreira/doppelganger/internal/scenarios"
+)
+
+// Scenario is an ordered conversation: each step only answers once the
+// previous one did. After the last step the scenario is finished, or
+// starts over when looping.
+type Scenario struct {
+	Name  string `json:"name"`
+	Loop  bool   `json:"loop,omitempty"`
+	Steps []Step `json:"steps"`
+}
+
+const scenarioFinished = "Finished"
+
+// Step is a mapping along with the endpoint it answers on.
+type Step struct {
+	Path string `json:"path"`
+	Verb string `json:"verb,omitempty"`
+	Mapping
+}
+
+func (step *Step) UnmarshalJSON(data []byte) error {
+	var route struct {
+		Path string `json:"path"`
+		Verb string `json:"verb"`
+	}
+	if err := json.Unmarshal(data, &route); err != nil {
+		return atBlock(data, err)
+	}
+	if route.Path == "" {
+		return atBlock(data, errors.New("scenario steps require a path"))
+	}
+	if route.Verb == "" {
+		route.Verb = "GET"
+	}
+	step.Path, step.Verb = route.Path, route.Verb
+
+	return json.Unmarshal(data, &step.Mapping)
+}
+
+// resolveScenarios compiles the scenario files of a server into mappings
+// placed ahead of the mappings of their endpoints.
+func resolveScenarios(configuration *Configuration, filePath string, defs definitions, fixturesDir string) error {
+	for _, file := range configuration.Scenarios {
+		path := resolvePath(filepath.Dir(filePath), file)
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		if err := src.substitute(defs); err != nil {
+			return err
+		}
+
+		var scenario Scenario
+		if err := json.Unmarshal(src.data, &scenario); err != nil {
+			return fmt.Errorf("error parsing scenario: %w", src.locate(err))
+		}
+		if scenario.Name == "" || len(scenario.Steps) == 0 {
+			return fmt.Errorf("scenario %s requires a name and steps", path)
+		}
+		for i := range scenario.Steps {
+			step := &scenario.Steps[i]
+			if err := resolveMappingFixtures(&step.Mapping, fixturesBase(filepath.Dir(path), fixturesDir)); err != nil {
+				return fmt.Errorf("%s: %s %s: %w", path, step.Verb, step.Path, err)
+			}
+		}
+
+		compileScenario(configuration, scenario)
+	}
+
+	configuration.Scenarios = nil
+	return nil
+}
+
+func compileScenario(configuration *Configuration, scenario Scenario) {
+	for i, step := range scenario.Steps {
+		mapping := step.Mapping
+		mapping.Scenario = scenario.Name
+		mapping.RequiredState = stepState(i)
+		switch {
+		case i < len(scenario.Steps)-1:
+			mapping.NewState = stepState(i + 1)
+		case scenario.Loop:
+			mapping.NewState = scenarios.Started
+		default:
+			mapping.NewState = scenarioFinished
+		}
+		if mapping.ID == "" {
+			mapping.ID = scenario.Name + " step " + strconv.Itoa(i+1)
+		}
+
+		endpoint := findEndpoint(configuration, step.Verb, step.Path)
+		endpoint.Mappings = append([]Mapping{mapping}, endpoint.Mappings...)
+	}
+}
+
+func stepState(index int) string {
+	if index == 0 {
+		return scenarios.Started
+	}
+	return "step " + strconv.Itoa(index+1)
+}
+
+func findEndpoint(configuration *Configuration, verb string, path string) *Endpoint {
+	for i := range configuration.Endpoints {
+		if configuration.Endpoints[i].Verb == verb && configuration.Endpoints[i].Path == path {
+			return &configuration.Endpoints[i]
+		}
+	}
+	configuration.Endpoints = append(configuration.Endpoints, Endpoint{Path: path, Verb: verb})
+	return &configuration.Endpoints[len(configuration.Endpoints)-1]
+}
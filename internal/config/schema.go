@@ -0,0 +1,8 @@
+package config
+
+import _ "embed"
+
+// Schema is the JSON schema describing configuration files.
+//
+//go:embed schema.json
+var Schema string
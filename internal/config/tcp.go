@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+// TCPRule answers the bytes received on a TCP connection. A rule without a
+// match answers whatever arrives.
+type TCPRule struct {
+	ID    string   `json:"id,omitempty"`
+	Match *Pattern `json:"match,omitempty"`
+	Reply *Payload `json:"reply,omitempty"`
+	// Delay is waited, in milliseconds, before replying.
+	Delay int `json:"delay,omitempty"`
+	// Close ends the connection after replying: "graceful" or "reset".
+	Close string `json:"close,omitempty"`
+}
+
+// Payload is a byte sequence written as text, hex or base64.
+type Payload struct {
+	Text   string `json:"text,omitempty"`
+	Hex    string `json:"hex,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+
+	Bytes []byte `json:"-"`
+}
+
+func (payload *Payload) UnmarshalJSON(data []byte) error {
+	type Alias Payload
+	aux := (*Alias)(payload)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return atBlock(data, err)
+	}
+
+	var err error
+	switch {
+	case payload.Hex != "":
+		payload.Bytes, err = hex.DecodeString(payload.Hex)
+	case payload.Base64 != "":
+		payload.Bytes, err = base64.StdEncoding.DecodeString(payload.Base64)
+	default:
+		payload.Bytes = []byte(payload.Text)
+	}
+	return atBlock(data, err)
+}
+
+// Pattern matches received bytes, either as a payload found anywhere in
+// them or as a regular expression.
+type Pattern struct {
+	Payload
+	Regex string `json:"regex,omitempty"`
+
+	Compiled *regexp.Regexp `json:"-"`
+}
+
+func (pattern *Pattern) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Regex string `json:"regex"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return atBlock(data, err)
+	}
+	if err := pattern.Payload.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	pattern.Regex = aux.Regex
+	if pattern.Regex != "" {
+		compiled, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return atBlock(data, err)
+		}
+		pattern.Compiled = compiled
+	} else if len(pattern.Bytes) == 0 {
+		return atBlock(data, errors.New("match requires text, hex, base64 or regex"))
+	}
+	return nil
+}
+
+// Find returns where the pattern ends in data, or -1.
+func (pattern *Pattern) Find(data []byte) int {
+	if pattern.Compiled != nil {
+		location := pattern.Compiled.FindIndex(data)
+		if location == nil {
+			return -1
+		}
+		return location[1]
+	}
+	index := bytes.Index(data, pattern.Bytes)
+	if index < 0 {
+		return -1
+	}
+	return index + len(pattern.Bytes)
+}
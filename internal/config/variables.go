@@ -0,0 +1,248 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// variablesKey is the top level object declaring the variables of a file.
+const variablesKey = "variables"
+
+var reference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// loadVariables reads the variables declared by a config file and applies
+// the overrides, parsed as the type of the declared value.
+func loadVariables(src *source, overrides map[string]string) (map[string]json.RawMessage, error) {
+	var declared struct {
+		Variables map[string]json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal(src.data, &declared); err != nil {
+		return nil, src.locate(err)
+	}
+
+	variables := declared.Variables
+	for name, value := range overrides {
+		current, ok := variables[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s", name)
+		}
+		typed, err := typedValue(current, value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		variables[name] = typed
+	}
+	return variables, nil
+}
+
+// typedValue reads value as the same JSON type as declared. Strings are
+// taken as they are, other types must be valid JSON.
+func typedValue(declared json.RawMessage, value string) (json.RawMessage, error) {
+	kind := jsonKind(declared)
+	if kind == "string" {
+		return json.Marshal(value)
+	}
+
+	if !json.Valid([]byte(value)) || jsonKind([]byte(value)) != kind {
+		return nil, fmt.Errorf("expected a %s, got %s", kind, value)
+	}
+	return json.RawMessage(value), nil
+}
+
+func jsonKind(data []byte) string {
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0 || trimmed[0] == 'n':
+		return "null"
+	case trimmed[0] == '"':
+		return "string"
+	case trimmed[0] == '{':
+		return "object"
+	case trimmed[0] == '[':
+		return "array"
+	case trimmed[0] == 't' || trimmed[0] == 'f':
+		return "boolean"
+	}
+	return "number"
+}
+
+// definitions are the variables and fragments declared by the main config
+// file, shared with its includes and scenarios.
+type definitions struct {
+	variables map[string]json.RawMessage
+	fragments map[string]json.RawMessage
+}
+
+// substitute replaces the ${name} references of every string of the file
+// and expands its fragment references. A string holding nothing but a
+// reference takes the type of the variable, like a number for a port,
+// others get the variable written as text. The points keep error messages
+// at the right line and column.
+func (s *source) substitute(defs definitions) error {
+	expand := bytes.Contains(s.data, []byte(`"`+fragmentKey+`"`))
+	if len(defs.variables) == 0 && !expand {
+		return nil
+	}
+
+	t := substitution{src: s, decoder: json.NewDecoder(bytes.NewReader(s.data)), defs: defs, expand: expand}
+	t.decoder.UseNumber()
+	if err := t.value(true); err != nil {
+		return err
+	}
+	s.data, s.points = t.buffer.Bytes(), t.points
+	return nil
+}
+
+type substitution struct {
+	src     *source
+	decoder *json.Decoder
+	// base is the offset in the source of the bytes being decoded.
+	base int
+	// at, when set, is where every token is reported to come from, for
+	// bytes that are not part of the source like expanded fragments.
+	at        *sourcePoint
+	defs      definitions
+	expand    bool
+	expanding []string
+	buffer    bytes.Buffer
+	points    []sourcePoint
+}
+
+func (t *substitution) value(root bool) error {
+	line, column := t.point()
+	if t.expand && t.peek() == '{' {
+		return t.object(root, line, column)
+	}
+	return t.token(root, line, column)
+}
+
+func (t *substitution) token(root bool, line, column int) error {
+	token, err := t.decoder.Token()
+	if err != nil {
+		return t.src.locate(err)
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		t.buffer.WriteByte(byte(value))
+		for i := 0; t.decoder.More(); i++ {
+			if i > 0 {
+				t.buffer.WriteByte(',')
+			}
+			if value == '{' {
+				if err := t.member(root); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := t.value(false); err != nil {
+				return err
+			}
+		}
+		closing, err := t.decoder.Token()
+		if err != nil {
+			return t.src.locate(err)
+		}
+		t.buffer.WriteByte(byte(closing.(json.Delim)))
+	case string:
+		replaced, err := t.replace(value)
+		if err != nil && t.at != nil {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%s:%d:%d: %w", t.src.path, line, column, err)
+		}
+		t.buffer.Write(replaced)
+	default:
+		encoded, _ := json.Marshal(value)
+		t.buffer.Write(encoded)
+	}
+	return nil
+}
+
+// member copies a key and its value, leaving the variables themselves as
+// they were declared.
+func (t *substitution) member(root bool) error {
+	t.point()
+	key, err := t.decoder.Token()
+	if err != nil {
+		return t.src.locate(err)
+	}
+	encoded, _ := json.Marshal(key)
+	t.buffer.Write(encoded)
+	t.buffer.WriteByte(':')
+
+	if root && (key == variablesKey || key == fragmentsKey) {
+		t.point()
+		var declared json.RawMessage
+		if err := t.decoder.Decode(&declared); err != nil {
+			return t.src.locate(err)
+		}
+		t.buffer.Write(declared)
+		return nil
+	}
+	return t.value(false)
+}
+
+// point records where the next token starts in the original file.
+func (t *substitution) point() (int, int) {
+	line, column := 0, 0
+	if t.at != nil {
+		line, column = t.at.line, t.at.column
+	} else {
+		line, column = t.src.position(t.offset())
+	}
+	t.points = append(t.points, sourcePoint{offset: t.buffer.Len(), line: line, column: column})
+	return line, column
+}
+
+// offset skips the separators after the last token to find where the next
+// one starts in the source.
+func (t *substitution) offset() int {
+	offset := t.base + int(t.decoder.InputOffset())
+	for offset < len(t.src.data) && strings.IndexByte(" \t\r\n,:", t.src.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// peek returns the first byte of the next token, without consuming it.
+func (t *substitution) peek() byte {
+	if offset := t.offset(); offset < len(t.src.data) {
+		return t.src.data[offset]
+	}
+	return 0
+}
+
+func (t *substitution) replace(value string) ([]byte, error) {
+	if len(t.defs.variables) == 0 {
+		return json.Marshal(value)
+	}
+	if match := reference.FindStringSubmatch(value); match != nil && match[0] == value {
+		variable, ok := t.defs.variables[match[1]]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %s", match[1])
+		}
+		return variable, nil
+	}
+
+	var err error
+	replaced := reference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := reference.FindStringSubmatch(ref)[1]
+		variable, ok := t.defs.variables[name]
+		if !ok {
+			err = fmt.Errorf("undefined variable %s", name)
+			return ref
+		}
+		var text string
+		if json.Unmarshal(variable, &text) == nil {
+			return text
+		}
+		return string(variable)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(replaced)
+}
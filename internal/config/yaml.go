@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func isYAML(filePath string) bool {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// source is a config file as JSON. YAML files are translated on load and
+// keep a map back to their own lines and columns for error messages.
+type source struct {
+	path   string
+	data   []byte
+	points []sourcePoint
+}
+
+type sourcePoint struct {
+	offset int
+	line   int
+	column int
+}
+
+func readSource(filePath string) (*source, error) {
+	file, err := readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(filePath, file)
+}
+
+func newSource(filePath string, file []byte) (*source, error) {
+	if !isYAML(filePath) {
+		return &source{path: filePath, data: file}, nil
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(file, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty document", filePath)
+	}
+
+	var translator yamlTranslator
+	if err := translator.node(document.Content[0]); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return &source{path: filePath, data: translator.buffer.Bytes(), points: translator.points}, nil
+}
+
+// position turns an offset of the JSON data into a line and column of the
+// original file.
+func (s *source) position(offset int) (int, int) {
+	if s.points == nil {
+		return position(s.data, offset)
+	}
+	i := sort.Search(len(s.points), func(i int) bool { return s.points[i].offset > offset }) - 1
+	if i < 0 {
+		return 1, 1
+	}
+	return s.points[i].line, s.points[i].column
+}
+
+type yamlTranslator struct {
+	buffer bytes.Buffer
+	points []sourcePoint
+}
+
+func (t *yamlTranslator) node(node *yaml.Node) error {
+	t.points = append(t.points, sourcePoint{offset: t.buffer.Len(), line: node.Line, column: node.Column})
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		return t.node(node.Alias)
+	case yaml.MappingNode:
+		t.buffer.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				t.buffer.WriteByte(',')
+			}
+			key := node.Content[i]
+			t.points = append(t.points, sourcePoint{offset: t.buffer.Len(), line: key.Line, column: key.Column})
+			encoded, _ := json.Marshal(key.Value)
+			t.buffer.Write(encoded)
+			t.buffer.WriteByte(':')
+			if err := t.node(node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		t.buffer.WriteByte('}')
+	case yaml.SequenceNode:
+		t.buffer.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				t.buffer.WriteByte(',')
+			}
+			if err := t.node(item); err != nil {
+				return err
+			}
+		}
+		t.buffer.WriteByte(']')
+	case yaml.ScalarNode:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		t.buffer.Write(encoded)
+	default:
+		return errors.New("unsupported YAML node")
+	}
+	return nil
+}
+
+// jsonToYAML rewrites JSON as YAML keeping the order of the keys.
+func jsonToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := jsonNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), encoder.Close()
+}
+
+func jsonNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if value == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			item, err := jsonNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		_, err := decoder.Token()
+		return node, err
+	case string:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		if strings.Contains(value, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// Convert reads a config file and writes it back as "json" or "yaml". The
+// file goes through the config types, so it is validated on the way and
+// comments are not kept.
+func Convert(filePath string, format string) ([]byte, error) {
+	src, err := readSource(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any = &Configuration{}
+	if isMultiServer(src.data) {
+		value = &Servers{}
+	}
+	if err := json.Unmarshal(src.data, value); err != nil {
+		return nil, src.locate(err)
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		return append(data, '\n'), nil
+	case "yaml", "yml":
+		return jsonToYAML(data)
+	}
+	return nil, errors.New("unknown format " + format + ", use json or yaml")
+}
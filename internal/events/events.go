@@ -0,0 +1,78 @@
+package events
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event is published by a mapping after it answered a request, standing in
+// for the message a real service would put on a broker.
+type Event struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Server string    `json:"server"`
+	Topic  string    `json:"topic"`
+	// Headers carry metadata such as a signature of Data.
+	Headers map[string]string `json:"headers,omitempty"`
+	Data    any               `json:"data"`
+}
+
+// Queue keeps the published events and hands new ones to subscribers.
+type Queue struct {
+	mutex       sync.Mutex
+	events      []Event
+	next        int
+	subscribers map[chan Event]bool
+}
+
+func NewQueue() *Queue {
+	return &Queue{subscribers: map[chan Event]bool{}}
+}
+
+func (q *Queue) Publish(event Event) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.next++
+	event.ID = strconv.Itoa(q.next)
+	q.events = append(q.events, event)
+	for subscriber := range q.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Slow subscribers miss events rather than block the request.
+		}
+	}
+}
+
+// Events returns the published events, oldest first.
+func (q *Queue) Events() []Event {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return append([]Event{}, q.events...)
+}
+
+// Clear drops every published event.
+func (q *Queue) Clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.events = nil
+}
+
+// Subscribe returns a channel receiving the events published from now on
+// and a function to stop receiving them.
+func (q *Queue) Subscribe() (<-chan Event, func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	subscriber := make(chan Event, 64)
+	q.subscribers[subscriber] = true
+	return subscriber, func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		delete(q.subscribers, subscriber)
+	}
+}
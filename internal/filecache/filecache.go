@@ -0,0 +1,112 @@
+package filecache
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cache keeps file contents in memory, evicting the least recently used
+// ones once maxBytes is exceeded. Entries are refreshed when the file's
+// modification time or size changes.
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  map[string]*entry
+	order    *list.List
+}
+
+type entry struct {
+	path    string
+	data    []byte
+	modTime time.Time
+	element *list.Element
+}
+
+type Content interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+type memoryContent struct {
+	*bytes.Reader
+}
+
+func (memoryContent) Close() error {
+	return nil
+}
+
+func New(maxBytes int64) *Cache {
+	return &Cache{maxBytes: maxBytes, entries: map[string]*entry{}, order: list.New()}
+}
+
+// Open returns the content of the file at path and its modification time.
+// Files larger than the cache are streamed from disk.
+func (c *Cache) Open(path string) (Content, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if cached, ok := c.lookup(path, info); ok {
+		return memoryContent{bytes.NewReader(cached.data)}, cached.modTime, nil
+	}
+
+	if info.Size() > c.maxBytes {
+		file, err := os.Open(path)
+		return file, info.ModTime(), err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	c.store(path, data, info.ModTime())
+
+	return memoryContent{bytes.NewReader(data)}, info.ModTime(), nil
+}
+
+func (c *Cache) lookup(path string, info os.FileInfo) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	if !cached.modTime.Equal(info.ModTime()) || int64(len(cached.data)) != info.Size() {
+		c.remove(cached)
+		return nil, false
+	}
+
+	c.order.MoveToFront(cached.element)
+	return cached, true
+}
+
+func (c *Cache) store(path string, data []byte, modTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.entries[path]; ok {
+		c.remove(existing)
+	}
+
+	for c.size+int64(len(data)) > c.maxBytes && c.order.Len() > 0 {
+		c.remove(c.order.Back().Value.(*entry))
+	}
+
+	cached := &entry{path: path, data: data, modTime: modTime}
+	cached.element = c.order.PushFront(cached)
+	c.entries[path] = cached
+	c.size += int64(len(data))
+}
+
+func (c *Cache) remove(cached *entry) {
+	c.order.Remove(cached.element)
+	delete(c.entries, cached.path)
+	c.size -= int64(len(cached.data))
+}
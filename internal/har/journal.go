@@ -0,0 +1,95 @@
+package har
+
+import (
+	"encoding/base64"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+)
+
+// FromJournal turns the HTTP requests of a journal into a HAR file. The
+// server name stands for the host, so reading the file back with suggest
+// keeps the requests of every server apart.
+func FromJournal(entries []journal.Entry, version string) File {
+	file := File{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "doppelganger", Version: version},
+		Entries: []Entry{},
+	}}
+	for _, entry := range entries {
+		if entry.Protocol != "" {
+			continue
+		}
+		file.Log.Entries = append(file.Log.Entries, fromEntry(entry))
+	}
+	return file
+}
+
+func fromEntry(entry journal.Entry) Entry {
+	target := url.URL{Scheme: "http", Host: entry.Server, Path: entry.Path, RawQuery: entry.Query}
+	request := Request{
+		Method:      entry.Method,
+		URL:         target.String(),
+		HTTPVersion: "HTTP/1.1",
+		Headers:     nameValues(entry.Headers),
+		QueryString: nameValues(target.Query()),
+		HeadersSize: -1,
+		BodySize:    len(entry.Body),
+	}
+	if entry.Body != "" {
+		request.PostData = &PostData{MimeType: http.Header(entry.Headers).Get("Content-Type"), Text: entry.Body}
+	}
+
+	response := Response{
+		Status:      entry.Status,
+		StatusText:  http.StatusText(entry.Status),
+		HTTPVersion: "HTTP/1.1",
+		Headers:     []NameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	var elapsed float64
+	if entry.Response != nil {
+		headers := http.Header(entry.Response.Headers)
+		response.Headers = nameValues(entry.Response.Headers)
+		response.RedirectURL = headers.Get("Location")
+		response.Content = content([]byte(entry.Response.Body), headers.Get("Content-Type"))
+		if !entry.Response.Truncated {
+			response.BodySize = len(entry.Response.Body)
+		}
+		elapsed = entry.Response.Time
+	}
+
+	return Entry{
+		StartedDateTime: entry.Time.Format(time.RFC3339Nano),
+		Time:            elapsed,
+		Request:         request,
+		Response:        response,
+		Timings:         Timings{Send: 0, Wait: elapsed, Receive: 0},
+	}
+}
+
+// content keeps text bodies as they are and base64 encodes the others.
+func content(body []byte, mimeType string) Content {
+	if utf8.Valid(body) {
+		return Content{Size: len(body), MimeType: mimeType, Text: string(body)}
+	}
+	return Content{Size: len(body), MimeType: mimeType, Text: base64.StdEncoding.EncodeToString(body), Encoding: "base64"}
+}
+
+// nameValues lists headers or query params sorted by name, as maps have
+// no order.
+func nameValues(values map[string][]string) []NameValue {
+	list := []NameValue{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		for _, value := range values[name] {
+			list = append(list, NameValue{Name: name, Value: value})
+		}
+	}
+	return list
+}
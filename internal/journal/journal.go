@@ -0,0 +1,224 @@
+package journal
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+)
+
+// Entry is a request received by one of the servers.
+type Entry struct {
+	Time     time.Time           `json:"time"`
+	Server   string              `json:"server"`
+	Protocol string              `json:"protocol,omitempty"` // empty for HTTP requests
+	Remote   string              `json:"remote,omitempty"`
+	Method   string              `json:"method,omitempty"`
+	Path     string              `json:"path,omitempty"`
+	Query    string              `json:"query,omitempty"`
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Body     string              `json:"body,omitempty"`
+	Status   int                 `json:"status,omitempty"`
+	Matched  bool                `json:"matched"`
+	Endpoint string              `json:"endpoint,omitempty"`
+	Mapping  string              `json:"mapping,omitempty"`
+	NearMiss *NearMiss           `json:"nearMiss,omitempty"`
+	Response *Response           `json:"response,omitempty"`
+}
+
+// Response is what an HTTP request was answered with. Bodies are cut after
+// MaxResponseBody bytes.
+type Response struct {
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      string              `json:"body,omitempty"`
+	Truncated bool                `json:"truncated,omitempty"`
+	// Time is how long the request took in milliseconds, delays included.
+	Time float64 `json:"time"`
+}
+
+// MaxResponseBody is how much of a response body entries keep.
+const MaxResponseBody = 1 << 20
+
+// NearMiss is the mapping that came closest to answering a request that
+// reached an endpoint but matched none of its mappings.
+type NearMiss struct {
+	Mapping string `json:"mapping"`
+	// Held counts the params that were true.
+	Held   int        `json:"held"`
+	Failed []Mismatch `json:"failed,omitempty"`
+	// State tells why the mapping's scenario kept it from answering.
+	State string `json:"state,omitempty"`
+	// Disabled tells the mapping was turned off through the admin API or
+	// is outside its activation window.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Mismatch is a param that was false, along with the values the request
+// gave to the expressions it compares.
+type Mismatch struct {
+	Param    int                    `json:"param"`
+	Expected json.RawMessage        `json:"expected"`
+	Actual   []expressions.Observed `json:"actual,omitempty"`
+}
+
+type Recorder interface {
+	Record(entry Entry)
+}
+
+// FileRecorder appends entries as JSON lines. Unless matched entries are
+// requested only unmatched requests are written.
+type FileRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	matched bool
+}
+
+func NewFileRecorder(path string, matched bool) (*FileRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRecorder{file: file, encoder: json.NewEncoder(file), matched: matched}, nil
+}
+
+func (r *FileRecorder) Record(entry Entry) {
+	if entry.Matched && !r.matched {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encoder.Encode(entry)
+}
+
+func (r *FileRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Limits bound what a MemoryRecorder keeps, zero values meaning no limit.
+// Bytes are estimated from the size of paths, headers and bodies.
+type Limits struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// Stats tell how full a MemoryRecorder is and how many entries it dropped.
+type Stats struct {
+	Entries    int    `json:"entries"`
+	Bytes      int64  `json:"bytes"`
+	MaxEntries int    `json:"maxEntries,omitempty"`
+	MaxBytes   int64  `json:"maxBytes,omitempty"`
+	Dropped    uint64 `json:"dropped"`
+}
+
+// MemoryRecorder keeps entries in memory, e.g. for the admin API. Once
+// over its limits the oldest entries are dropped to make room.
+type MemoryRecorder struct {
+	mu      sync.Mutex
+	entries []Entry
+	limits  Limits
+	bytes   int64
+	dropped uint64
+}
+
+func NewMemoryRecorder(limits Limits) *MemoryRecorder {
+	return &MemoryRecorder{limits: limits}
+}
+
+func (r *MemoryRecorder) Record(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	r.bytes += entrySize(entry)
+
+	for len(r.entries) > 0 && r.full() {
+		if r.dropped == 0 {
+			log.Printf("Journal full, dropping the oldest entries (max %d entries, %d bytes)\n", r.limits.MaxEntries, r.limits.MaxBytes)
+		}
+		r.bytes -= entrySize(r.entries[0])
+		r.entries[0] = Entry{}
+		r.entries = r.entries[1:]
+		r.dropped++
+	}
+}
+
+func (r *MemoryRecorder) full() bool {
+	return r.limits.MaxEntries > 0 && len(r.entries) > r.limits.MaxEntries ||
+		r.limits.MaxBytes > 0 && r.bytes > r.limits.MaxBytes
+}
+
+// Stats returns how many entries are kept and were dropped so far.
+func (r *MemoryRecorder) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Stats{Entries: len(r.entries), Bytes: r.bytes, MaxEntries: r.limits.MaxEntries, MaxBytes: r.limits.MaxBytes, Dropped: r.dropped}
+}
+
+// entrySize estimates the memory an entry holds on to.
+func entrySize(entry Entry) int64 {
+	size := 256 + len(entry.Path) + len(entry.Query) + len(entry.Body) + headersSize(entry.Headers)
+	if entry.Response != nil {
+		size += len(entry.Response.Body) + headersSize(entry.Response.Headers)
+	}
+	return int64(size)
+}
+
+func headersSize(headers map[string][]string) int {
+	size := 0
+	for name, values := range headers {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return size
+}
+
+// Entries returns the recorded entries, oldest first.
+func (r *MemoryRecorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry{}, r.entries...)
+}
+
+func (r *MemoryRecorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+	r.bytes = 0
+}
+
+// Recorders hands each entry to all of its recorders.
+type Recorders []Recorder
+
+func (recorders Recorders) Record(entry Entry) {
+	for _, recorder := range recorders {
+		recorder.Record(entry)
+	}
+}
+
+// ReadFile loads every entry of a JSON lines journal file.
+func ReadFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var entry Entry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package kv
+
+import (
+	"maps"
+	"sync"
+)
+
+// Store holds values set through the admin API, read by templates and
+// expressions.
+type Store struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+func NewStore() *Store {
+	return &Store{values: map[string]any{}}
+}
+
+func (s *Store) Get(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *Store) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+}
+
+// All returns a copy of every value, by key.
+func (s *Store) All() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.values)
+}
+
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = map[string]any{}
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journald sends every write as one entry using the native journal
+// protocol, so multi-line messages stay in a single entry.
+type journald struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func newJournald() (io.WriteCloser, error) {
+	addr := &net.UnixAddr{Name: journaldSocket, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &journald{conn: conn, addr: addr}
+	if _, err := writer.Write([]byte("doppelganger logging to journald\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *journald) Write(data []byte) (int, error) {
+	message := bytes.TrimRight(data, "\n")
+
+	var entry bytes.Buffer
+	entry.WriteString("SYSLOG_IDENTIFIER=doppelganger\nPRIORITY=6\nMESSAGE\n")
+	binary.Write(&entry, binary.LittleEndian, uint64(len(message)))
+	entry.Write(message)
+	entry.WriteByte('\n')
+
+	if _, err := w.conn.WriteToUnix(entry.Bytes(), w.addr); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *journald) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+func newJournald() (io.WriteCloser, error) {
+	return nil, errors.New("journald output is only supported on linux")
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Options describes where logs are written. Output is one of "stdout",
+// "stderr", "file:<path>", "syslog" or "journald".
+type Options struct {
+	Output     string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// Setup opens the configured log target and routes the standard logger and
+// gin's request logs to it. The returned closer flushes and releases it.
+func Setup(options Options) (io.Closer, error) {
+	writer, timestamps, err := open(options)
+	if err != nil {
+		return nil, err
+	}
+
+	log.SetOutput(writer)
+	if !timestamps {
+		log.SetFlags(0)
+	}
+	gin.DefaultWriter = writer
+	gin.DefaultErrorWriter = writer
+
+	return writer, nil
+}
+
+func open(options Options) (io.WriteCloser, bool, error) {
+	switch {
+	case options.Output == "" || options.Output == "stdout":
+		return nopCloser{os.Stdout}, true, nil
+	case options.Output == "stderr":
+		return nopCloser{os.Stderr}, true, nil
+	case strings.HasPrefix(options.Output, "file:"):
+		path := strings.TrimPrefix(options.Output, "file:")
+		if path == "" {
+			return nil, false, errors.New("file log output needs a path, e.g. file:/var/log/doppelganger.log")
+		}
+		writer, err := newRotatingFile(path, options.MaxSize, options.MaxBackups)
+		return writer, true, err
+	case options.Output == "syslog":
+		writer, err := newSyslog()
+		return writer, false, err
+	case options.Output == "journald":
+		writer, err := newJournald()
+		return writer, false, err
+	}
+	return nil, false, errors.New("unknown log output " + options.Output)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a file and, once it grows past maxSize bytes,
+// renames it to <path>.1 (shifting older backups up to maxBackups) and
+// starts a fresh one. A maxSize of zero disables rotation.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	writer := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := writer.openFile(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *rotatingFile) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(data)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingFile) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func (w *rotatingFile) openFile() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotatingFile) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if w.maxBackups > 0 {
+		os.Remove(backupName(w.path, w.maxBackups))
+		for i := w.maxBackups - 1; i >= 1; i-- {
+			os.Rename(backupName(w.path, i), backupName(w.path, i+1))
+		}
+		if err := os.Rename(w.path, backupName(w.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Truncate(w.path, 0); err != nil {
+		return err
+	}
+
+	return w.openFile()
+}
+
+func backupName(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
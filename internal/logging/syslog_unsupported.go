@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+func newSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Buckets are the upper bounds, in milliseconds, of the latency histogram.
+var Buckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+type Histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+type Bucket struct {
+	LessOrEqual float64 `json:"le"`
+	Count       uint64  `json:"count"`
+}
+
+type HistogramSnapshot struct {
+	Count   uint64   `json:"count"`
+	SumMs   float64  `json:"sumMs"`
+	MaxMs   float64  `json:"maxMs"`
+	Buckets []Bucket `json:"buckets"`
+	// Overflow counts observations above the last bucket.
+	Overflow uint64 `json:"overflow"`
+}
+
+// Registry keeps one latency histogram per server and endpoint.
+type Registry struct {
+	mu         sync.Mutex
+	histograms map[string]map[string]*Histogram
+}
+
+func NewRegistry() *Registry {
+	return &Registry{histograms: map[string]map[string]*Histogram{}}
+}
+
+func (r *Registry) Observe(server string, endpoint string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endpoints, ok := r.histograms[server]
+	if !ok {
+		endpoints = map[string]*Histogram{}
+		r.histograms[server] = endpoints
+	}
+	histogram, ok := endpoints[endpoint]
+	if !ok {
+		histogram = &Histogram{counts: make([]uint64, len(Buckets)+1)}
+		endpoints[endpoint] = histogram
+	}
+
+	histogram.observe(duration)
+}
+
+// Snapshot returns cumulative bucket counts keyed by server then endpoint.
+func (r *Registry) Snapshot() map[string]map[string]HistogramSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]map[string]HistogramSnapshot, len(r.histograms))
+	for server, endpoints := range r.histograms {
+		snapshot[server] = make(map[string]HistogramSnapshot, len(endpoints))
+		for endpoint, histogram := range endpoints {
+			snapshot[server][endpoint] = histogram.snapshot()
+		}
+	}
+	return snapshot
+}
+
+func (h *Histogram) observe(duration time.Duration) {
+	ms := float64(duration) / float64(time.Millisecond)
+
+	index := len(Buckets)
+	for i, bound := range Buckets {
+		if ms <= bound {
+			index = i
+			break
+		}
+	}
+
+	h.counts[index]++
+	h.count++
+	h.sum += duration
+	if duration > h.max {
+		h.max = duration
+	}
+}
+
+func (h *Histogram) snapshot() HistogramSnapshot {
+	buckets := make([]Bucket, len(Buckets))
+	var cumulative uint64
+	for i, bound := range Buckets {
+		cumulative += h.counts[i]
+		buckets[i] = Bucket{LessOrEqual: bound, Count: cumulative}
+	}
+
+	return HistogramSnapshot{
+		Count:    h.count,
+		SumMs:    float64(h.sum) / float64(time.Millisecond),
+		MaxMs:    float64(h.max) / float64(time.Millisecond),
+		Buckets:  buckets,
+		Overflow: h.counts[len(Buckets)],
+	}
+}
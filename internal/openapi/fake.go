@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Recursive schemas, like trees, only nest their optional properties up to
+// optionalDepth and stop for good at maxDepth.
+const (
+	optionalDepth = 3
+	maxDepth      = 8
+)
+
+// fake makes up a value matching the schema. Examples, defaults and enums
+// are used when given, otherwise values follow the type and format.
+func fake(schema *openapi3.Schema, name string, depth int) any {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := map[string]any{}
+		for _, part := range schema.AllOf {
+			if object, ok := fake(part.Value, name, depth+1).(map[string]any); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return fake(schema.OneOf[0].Value, name, depth+1)
+	case len(schema.AnyOf) > 0:
+		return fake(schema.AnyOf[0].Value, name, depth+1)
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject) || schema.Type == nil && len(schema.Properties) > 0:
+		object := map[string]any{}
+		for property, ref := range schema.Properties {
+			if ref.Value != nil && ref.Value.WriteOnly || depth >= optionalDepth && !slices.Contains(schema.Required, property) {
+				continue
+			}
+			object[property] = fake(ref.Value, property, depth+1)
+		}
+		return object
+	case schema.Type.Is(openapi3.TypeArray):
+		if schema.Items == nil {
+			return []any{}
+		}
+		count := max(schema.MinItems, 1)
+		if schema.MaxItems != nil {
+			count = min(count, *schema.MaxItems)
+		}
+		items := make([]any, count)
+		for i := range items {
+			items[i] = fake(schema.Items.Value, name, depth+1)
+		}
+		return items
+	case schema.Type.Is(openapi3.TypeInteger):
+		return int(number(schema))
+	case schema.Type.Is(openapi3.TypeNumber):
+		return number(schema)
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	case schema.Type.Is(openapi3.TypeString):
+		return fakeString(schema, name)
+	}
+	return nil
+}
+
+func number(schema *openapi3.Schema) float64 {
+	switch {
+	case schema.Min != nil:
+		if schema.ExclusiveMin {
+			return *schema.Min + 1
+		}
+		return *schema.Min
+	case schema.Max != nil && *schema.Max < 1:
+		return *schema.Max
+	}
+	return 1
+}
+
+func fakeString(schema *openapi3.Schema, name string) string {
+	var value string
+	switch schema.Format {
+	case "date-time":
+		value = "2024-01-01T00:00:00Z"
+	case "date":
+		value = "2024-01-01"
+	case "time":
+		value = "00:00:00"
+	case "email":
+		value = "user@example.com"
+	case "uuid":
+		value = "123e4567-e89b-12d3-a456-426614174000"
+	case "uri", "url":
+		value = "https://example.com"
+	case "hostname":
+		value = "example.com"
+	case "ipv4":
+		value = "192.0.2.1"
+	case "ipv6":
+		value = "2001:db8::1"
+	case "byte":
+		value = "ZG9wcGVsZ2FuZ2Vy"
+	default:
+		value = name
+		if value == "" {
+			value = "string"
+		}
+	}
+
+	if length := int(schema.MinLength); len(value) < length {
+		value += strings.Repeat("x", length-len(value))
+	}
+	if schema.MaxLength != nil && uint64(len(value)) > *schema.MaxLength {
+		value = value[:*schema.MaxLength]
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+func init() {
+	// Keep validation errors to a single line, without the schema and value.
+	openapi3.SchemaErrorDetailsDisabled = true
+}
+
+// Spec is an OpenAPI 3 document requests are routed against. Server URLs
+// are ignored, paths are served as declared.
+type Spec struct {
+	document *openapi3.T
+	router   routers.Router
+}
+
+// Load reads a JSON or YAML OpenAPI 3 document.
+func Load(path string) (*Spec, error) {
+	loader := openapi3.NewLoader()
+	document, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := document.Validate(loader.Context); err != nil {
+		return nil, err
+	}
+
+	document.Servers = nil
+	router, err := legacy.NewRouter(document)
+	if err != nil {
+		return nil, err
+	}
+	return &Spec{document: document, router: router}, nil
+}
+
+// Synthesize makes up a response for a request on one of the spec's
+// operations, using its first success response, or the default one. It
+// reports false for requests the spec does not know.
+func (s *Spec) Synthesize(request *http.Request) (int, any, bool) {
+	route, _, err := s.router.FindRoute(request)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	status, response := successResponse(route.Operation.Responses)
+	if response == nil {
+		return 0, nil, false
+	}
+
+	media := response.Content.Get("application/json")
+	if media == nil {
+		return status, nil, true
+	}
+	if media.Example != nil {
+		return status, media.Example, true
+	}
+	for _, name := range sortedKeys(media.Examples) {
+		if example := media.Examples[name]; example.Value != nil && example.Value.Value != nil {
+			return status, example.Value.Value, true
+		}
+	}
+	if media.Schema == nil {
+		return status, nil, true
+	}
+	return status, fake(media.Schema.Value, "", 0), true
+}
+
+func successResponse(responses *openapi3.Responses) (int, *openapi3.Response) {
+	if responses == nil {
+		return 0, nil
+	}
+
+	codes := sortedKeys(responses.Map())
+	for _, code := range codes {
+		status, err := strconv.Atoi(code)
+		if err == nil && status >= 200 && status < 300 {
+			return status, responses.Value(code).Value
+		}
+	}
+	if response := responses.Default(); response != nil {
+		return http.StatusOK, response.Value
+	}
+	return 0, nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks a response against the spec's declaration of the
+// request's operation.
+func (s *Spec) Validate(request *http.Request, status int, header http.Header, body []byte) error {
+	route, params, err := s.router.FindRoute(request)
+	if err != nil {
+		return fmt.Errorf("%s %s is not declared in the spec", request.Method, request.URL.Path)
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: request, PathParams: params, Route: route},
+		Status:                 status,
+		Header:                 header,
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	input.SetBodyBytes(body)
+	return openapi3filter.ValidateResponse(request.Context(), input)
+}
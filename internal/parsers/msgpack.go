@@ -0,0 +1,50 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// parseMsgpack decodes a MessagePack body and goes through JSON so values
+// read the same as they would in a JSON body.
+func parseMsgpack(data []byte, contentType string) (map[string]any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (any, error) {
+		return d.DecodeUntypedMap()
+	})
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(stringKeys(value))
+	if err != nil {
+		return nil, err
+	}
+	return parseJson(encoded, contentType)
+}
+
+// stringKeys turns the untyped maps msgpack decodes into maps JSON can
+// encode.
+func stringKeys(value any) any {
+	switch value := value.(type) {
+	case map[any]any:
+		converted := make(map[string]any, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return converted
+	case []any:
+		for i, item := range value {
+			value[i] = stringKeys(item)
+		}
+	}
+	return value
+}
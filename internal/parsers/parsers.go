@@ -0,0 +1,202 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+// Parser turns a raw request body into the map read by BODY expressions.
+// contentType is the full Content-Type header, parameters included.
+type Parser func(data []byte, contentType string) (map[string]any, error)
+
+var ParserRegistry map[string]Parser
+
+const maxMultipartMemory = 32 << 20
+
+func init() {
+	ParserRegistry = map[string]Parser{
+		"application/json":                  parseJson,
+		"application/x-www-form-urlencoded": parseForm,
+		"multipart/form-data":               parseMultipartForm,
+		"application/xml":                   parseXml,
+		"text/xml":                          parseXml,
+		"text/plain":                        parseText,
+		"application/msgpack":               parseMsgpack,
+		"application/x-msgpack":             parseMsgpack,
+	}
+}
+
+// Register adds or replaces the parser used for a media type.
+func Register(mediaType string, parser Parser) {
+	ParserRegistry[strings.ToLower(mediaType)] = parser
+}
+
+// Lookup finds the parser for a Content-Type header. Structured syntax
+// suffixes (e.g. application/vnd.acme+json) fall back to the parser of
+// their base format.
+func Lookup(contentType string) (Parser, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	if parser, ok := ParserRegistry[mediaType]; ok {
+		return parser, true
+	}
+
+	if _, suffix, found := strings.Cut(mediaType, "+"); found {
+		parser, ok := ParserRegistry["application/"+suffix]
+		return parser, ok
+	}
+
+	return nil, false
+}
+
+// Parse runs the registered parser for contentType. A nil body is
+// returned when no parser is registered for it.
+func Parse(data []byte, contentType string) (map[string]any, error) {
+	parser, ok := Lookup(contentType)
+	if !ok {
+		return nil, nil
+	}
+	return parser(data, contentType)
+}
+
+func parseJson(data []byte, contentType string) (map[string]any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func parseForm(data []byte, contentType string) (map[string]any, error) {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return squashFormData(values), nil
+}
+
+func parseMultipartForm(data []byte, contentType string) (map[string]any, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if params["boundary"] == "" {
+		return nil, errors.New("multipart body without boundary")
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(data), params["boundary"]).ReadForm(maxMultipartMemory)
+	if err != nil {
+		return nil, err
+	}
+	defer form.RemoveAll()
+
+	return squashFormData(form.Value), nil
+}
+
+func squashFormData(formData url.Values) map[string]any {
+	result := make(map[string]any)
+
+	for key, values := range formData {
+		if len(values) > 1 {
+			result[key] = values // keep as []string
+		} else {
+			result[key] = values[0] // collapse single value
+		}
+	}
+	return result
+}
+
+func parseText(data []byte, contentType string) (map[string]any, error) {
+	return map[string]any{"text": string(data)}, nil
+}
+
+// parseXml maps the children of the root element by tag name. Elements
+// holding only text become strings, repeated elements become lists and
+// attributes are stored under "@name".
+func parseXml(data []byte, contentType string) (map[string]any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := readXmlElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			if body, ok := value.(map[string]any); ok {
+				return body, nil
+			}
+			return map[string]any{"#text": value}, nil
+		}
+	}
+}
+
+func readXmlElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	children := make(map[string]any)
+	for _, attr := range start.Attr {
+		children["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := readXmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			addXmlChild(children, t.Name.Local, value)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(children) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				children["#text"] = content
+			}
+			return children, nil
+		}
+	}
+}
+
+func addXmlChild(children map[string]any, name string, value any) {
+	existing, found := children[name]
+	if !found {
+		children[name] = value
+		return
+	}
+
+	if list, ok := existing.([]any); ok {
+		children[name] = append(list, value)
+		return
+	}
+	children[name] = []any{existing, value}
+}
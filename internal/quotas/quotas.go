@@ -0,0 +1,91 @@
+package quotas
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Usage is how much of a quota a client used. Client is empty unless
+// servers partition their quotas by client key.
+type Usage struct {
+	Server string    `json:"server"`
+	Client string    `json:"client,omitempty"`
+	Quota  string    `json:"quota"`
+	Used   int       `json:"used"`
+	Since  time.Time `json:"since"`
+}
+
+type key struct {
+	server, client, quota string
+}
+
+type usage struct {
+	used  int
+	since time.Time
+}
+
+// Store counts the requests answered under every quota, per client.
+type Store struct {
+	mu    sync.Mutex
+	usage map[key]*usage
+}
+
+func NewStore() *Store {
+	return &Store{usage: map[key]*usage{}}
+}
+
+// Take counts a request against a quota of limit requests, renewed every
+// window when not zero. It returns false once the quota is used up, along
+// with when it renews, the zero time when it never does.
+func (s *Store) Take(server string, client string, quota string, limit int, window time.Duration) (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	k := key{server, client, quota}
+	current, ok := s.usage[k]
+	if !ok || window > 0 && now.Sub(current.since) >= window {
+		current = &usage{since: now}
+		s.usage[k] = current
+	}
+
+	var renews time.Time
+	if window > 0 {
+		renews = current.since.Add(window)
+	}
+	if current.used >= limit {
+		return false, renews
+	}
+	current.used++
+	return true, renews
+}
+
+// Usage returns the quotas used so far, sorted by server, client and quota.
+func (s *Store) Usage() []Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usages := make([]Usage, 0, len(s.usage))
+	for k, current := range s.usage {
+		usages = append(usages, Usage{Server: k.server, Client: k.client, Quota: k.quota, Used: current.used, Since: current.since})
+	}
+	slices.SortFunc(usages, func(a, b Usage) int {
+		return cmp.Or(cmp.Compare(a.Server, b.Server), cmp.Compare(a.Client, b.Client), cmp.Compare(a.Quota, b.Quota))
+	})
+	return usages
+}
+
+// Reset renews the quotas named quota, or all of them when empty, of a
+// client, or of every client when all is set.
+func (s *Store) Reset(quota string, client string, all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.usage {
+		if (quota == "" || k.quota == quota) && (all || k.client == client) {
+			delete(s.usage, k)
+		}
+	}
+}
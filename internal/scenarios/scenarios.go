@@ -0,0 +1,98 @@
+package scenarios
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Started is the state every scenario begins in.
+const Started = "Started"
+
+// State is where a scenario stands for a client. Client is empty unless
+// servers partition their states by client key.
+type State struct {
+	Client   string `json:"client,omitempty"`
+	Scenario string `json:"scenario"`
+	State    string `json:"state"`
+}
+
+type key struct {
+	client, scenario string
+}
+
+// Store keeps the current state of every scenario, per client.
+type Store struct {
+	mu     sync.Mutex
+	states map[key]string
+}
+
+func NewStore() *Store {
+	return &Store{states: map[key]string{}}
+}
+
+func (s *Store) State(client string, scenario string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state, ok := s.states[key{client, scenario}]; ok {
+		return state
+	}
+	return Started
+}
+
+func (s *Store) SetState(client string, scenario string, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key{client, scenario}] = state
+}
+
+// Advance moves a scenario to next when it still is in from, so two
+// concurrent requests cannot both answer the same step.
+func (s *Store) Advance(client string, scenario string, from string, next string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{client, scenario}
+	current, ok := s.states[k]
+	if !ok {
+		current = Started
+	}
+	if from != "" && current != from {
+		return false
+	}
+	if next != "" {
+		s.states[k] = next
+	}
+	return true
+}
+
+// States returns the scenarios that left their initial state, sorted by
+// client then scenario.
+func (s *Store) States() []State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]State, 0, len(s.states))
+	for k, state := range s.states {
+		states = append(states, State{Client: k.client, Scenario: k.scenario, State: state})
+	}
+	slices.SortFunc(states, func(a, b State) int {
+		return cmp.Or(cmp.Compare(a.Client, b.Client), cmp.Compare(a.Scenario, b.Scenario))
+	})
+	return states
+}
+
+// Reset puts every scenario of a client back in its initial state, or the
+// scenarios of every client when all is set.
+func (s *Store) Reset(client string, all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.states {
+		if all || k.client == client {
+			delete(s.states, k)
+		}
+	}
+}
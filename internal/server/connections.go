@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+type requestCountKey struct{}
+
+// countRequests gives every connection a counter of the requests it
+// carried.
+func countRequests(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, requestCountKey{}, new(atomic.Int64))
+}
+
+// MaxRequestsPerConnection closes connections after max requests, so
+// clients have to open new ones. HTTP/2 connections are left alone.
+func MaxRequestsPerConnection(max int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, ok := c.Request.Context().Value(requestCountKey{}).(*atomic.Int64)
+		if ok && c.Request.ProtoMajor == 1 && count.Add(1) >= int64(max) {
+			c.Header("Connection", "close")
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const datasetsKey = "doppelganger.datasets"
+
+// Datasets makes the records of the server's datasets available to
+// templates.
+func Datasets(datasets map[string]*config.Dataset) gin.HandlerFunc {
+	records := make(map[string][]map[string]any, len(datasets))
+	for name, dataset := range datasets {
+		records[name] = dataset.Records
+	}
+	return func(c *gin.Context) {
+		c.Set(datasetsKey, records)
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+const publisherKey = "doppelganger.publisher"
+
+type publisher struct {
+	queue  *events.Queue
+	server string
+}
+
+func Events(queue *events.Queue, serverName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(publisherKey, publisher{queue: queue, server: serverName})
+		c.Next()
+	}
+}
+
+// publish appends the events of a mapping to the queue. Templates see the
+// same data as the response.
+func publish(c *gin.Context, body map[string]any, publishes []config.Publish) {
+	value, ok := c.Get(publisherKey)
+	if !ok {
+		return
+	}
+	p := value.(publisher)
+
+	for _, event := range publishes {
+		data := event.Content.Data
+		if event.Content.CompiledTemplate != nil {
+			var err error
+			data, err = event.Content.CompiledTemplate.Render(templateData(c, body))
+			if err != nil {
+				log.Println("Error rendering event template: " + err.Error())
+				continue
+			}
+		}
+		published := events.Event{Time: time.Now(), Server: p.server, Topic: event.Topic, Data: data}
+		if event.Signature != nil {
+			encoded, _ := json.Marshal(data)
+			published.Headers = map[string]string{event.Signature.Header: event.Signature.Sign(encoded)}
+		}
+		p.queue.Publish(published)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// execRequest is what EXEC commands read on their standard input.
+type execRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Params  map[string]string   `json:"params"`
+	Query   map[string][]string `json:"query"`
+	Headers map[string][]string `json:"headers"`
+	Body    map[string]any      `json:"body"`
+	RawBody string              `json:"rawBody"`
+}
+
+// checkCommands makes sure every EXEC content of the server runs one of the
+// allowed commands.
+func checkCommands(configuration *config.Configuration, allowed []string) error {
+	for _, endpoint := range configuration.Endpoints {
+		for i := range endpoint.Mappings {
+			err := endpoint.Mappings[i].WalkContents(func(content *config.Content) error {
+				if content.Type != config.ContentTypeExec {
+					return nil
+				}
+				command := content.Data.(config.DataExec).Command
+				if !slices.Contains(allowed, command) {
+					return fmt.Errorf("EXEC command %s of %s %s is not allowed, add it to -allow-exec", command, endpoint.Verb, endpoint.Path)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serveExec runs the command and answers with its standard output. Failing
+// or timed out commands are answered with a 500.
+func serveExec(c *gin.Context, code int, body map[string]any, data config.DataExec) {
+	params := make(map[string]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = param.Value
+	}
+	input, _ := json.Marshal(execRequest{
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Params:  params,
+		Query:   c.Request.URL.Query(),
+		Headers: c.Request.Header,
+		Body:    body,
+		RawBody: rawBody(c),
+	})
+
+	timeout := time.Duration(data.Timeout) * time.Millisecond
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, data.Command, data.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			err = fmt.Errorf("%w: %s", err, output)
+		}
+		log.Println("Error running EXEC command " + data.Command + ": " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(code, data.ContentType, stdout.Bytes())
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/filecache"
+	"github.com/gin-gonic/gin"
+)
+
+const fileCacheKey = "doppelganger.fileCache"
+
+func FileCache(cache *filecache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(fileCacheKey, cache)
+		c.Next()
+	}
+}
+
+// serveFile answers with a file using the configured status code. Range
+// and conditional requests are handled by http.ServeContent, which streams
+// files that are not cached straight from disk.
+func serveFile(c *gin.Context, code int, file config.DataFile) {
+	if c.GetHeader("Range") != "" {
+		delay(c, time.Duration(file.RangeDelay)*time.Millisecond)
+	}
+
+	var writer http.ResponseWriter = statusWriter{ResponseWriter: c.Writer, code: code}
+	var aborter *abortWriter
+	if file.AbortAfter > 0 {
+		aborter = &abortWriter{ResponseWriter: writer, remaining: file.AbortAfter}
+		writer = aborter
+	}
+
+	if file.Encoding == config.EncodingGzip {
+		serveCompressed(c, writer, file.Path)
+	} else if cache, ok := c.Get(fileCacheKey); ok {
+		content, modTime, err := cache.(*filecache.Cache).Open(file.Path)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer content.Close()
+
+		http.ServeContent(writer, c.Request, filepath.Base(file.Path), modTime, content)
+	} else {
+		http.ServeFile(writer, c.Request, file.Path)
+	}
+
+	if aborter != nil && aborter.aborted {
+		dropConnection(c)
+	}
+}
+
+// serveCompressed answers with a gzip file as it is, along with a gzip
+// Content-Encoding, to clients accepting it, and streams it decompressed
+// to others, without Range support. Its Content-Type comes from the name
+// the file has without ".gz".
+func serveCompressed(c *gin.Context, writer http.ResponseWriter, path string) {
+	content, modTime, err := openFile(c, path)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer content.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	header := writer.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+		header.Set("Content-Encoding", "gzip")
+		http.ServeContent(writer, c.Request, name, modTime, content)
+		return
+	}
+
+	reader, err := gzip.NewReader(content)
+	if err != nil {
+		log.Printf("Error decompressing %s: %s\n", path, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	header.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	writer.WriteHeader(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(writer, reader); err != nil && !errors.Is(err, errAborted) {
+		log.Printf("Error decompressing %s: %s\n", path, err)
+	}
+}
+
+// openFile reads a file through the file cache when there is one.
+func openFile(c *gin.Context, path string) (filecache.Content, time.Time, error) {
+	if cache, ok := c.Get(fileCacheKey); ok {
+		return cache.(*filecache.Cache).Open(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, time.Time{}, err
+	}
+	return file, info.ModTime(), nil
+}
+
+// acceptsGzip tells whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(coding, "gzip") && coding != "*" {
+			continue
+		}
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if quality, err := strconv.ParseFloat(value, 64); err == nil && quality == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// dropConnection closes the underlying connection after flushing what was
+// written, so the client sees a truncated response.
+func dropConnection(c *gin.Context) {
+	c.Writer.Flush()
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveGraphQL answers the operation sent in the body, or in the query
+// string for GET requests, with values made up from the schema.
+func serveGraphQL(c *gin.Context, code int, body map[string]any, schema config.DataGraphQL) {
+	query, operationName := c.Query("query"), c.Query("operationName")
+	if body != nil {
+		query, _ = body["query"].(string)
+		operationName, _ = body["operationName"].(string)
+	}
+	c.JSON(code, schema.Compiled.Execute(query, operationName))
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const replayedHeader = "Idempotent-Replayed"
+
+// recordedResponse is the first answer given to an idempotency key, nil
+// while that request is in progress.
+type recordedResponse struct {
+	fingerprint [32]byte
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+type idempotencyKey struct {
+	client, method, path, key string
+}
+
+type idempotencyEntry struct {
+	key      idempotencyKey
+	added    time.Time
+	response *recordedResponse
+	element  *list.Element
+}
+
+// idempotencyKeys remembers the keys seen, oldest first, forgetting them
+// after ttl and beyond maxKeys, zero values meaning no limit.
+type idempotencyKeys struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxKeys int
+	entries map[idempotencyKey]*idempotencyEntry
+	order   *list.List
+}
+
+// claim returns the response recorded for key, nil while in progress, or
+// reserves key for a request in progress, returning its new entry.
+func (k *idempotencyKeys) claim(key idempotencyKey) (*idempotencyEntry, *recordedResponse, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	for front := k.order.Front(); front != nil; front = k.order.Front() {
+		oldest := front.Value.(*idempotencyEntry)
+		if k.ttl == 0 || now.Sub(oldest.added) < k.ttl {
+			break
+		}
+		k.forget(oldest)
+	}
+	if existing, ok := k.entries[key]; ok {
+		return nil, existing.response, true
+	}
+
+	entry := &idempotencyEntry{key: key, added: now}
+	entry.element = k.order.PushBack(entry)
+	k.entries[key] = entry
+	for k.maxKeys > 0 && len(k.entries) > k.maxKeys {
+		k.forget(k.order.Front().Value.(*idempotencyEntry))
+	}
+	return entry, nil, false
+}
+
+// settle records the response to a claimed entry, or forgets the entry
+// when response is nil.
+func (k *idempotencyKeys) settle(entry *idempotencyEntry, response *recordedResponse) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.entries[entry.key] != entry {
+		// Forgotten while in progress.
+		return
+	}
+	if response == nil {
+		k.forget(entry)
+		return
+	}
+	entry.response = response
+}
+
+func (k *idempotencyKeys) forget(entry *idempotencyEntry) {
+	k.order.Remove(entry.element)
+	delete(k.entries, entry.key)
+}
+
+// Idempotency answers requests repeating the key in header with the
+// response to the first one instead of matching them again. Reusing a key
+// with a different body is answered with a 422, and with a 409 while the
+// first request is in progress. Keys are forgotten after ttl and beyond
+// maxKeys, the oldest first, zero values meaning no limit.
+func Idempotency(header string, ttl time.Duration, maxKeys int) gin.HandlerFunc {
+	keys := &idempotencyKeys{ttl: ttl, maxKeys: maxKeys, entries: map[idempotencyKey]*idempotencyEntry{}, order: list.New()}
+
+	return func(c *gin.Context) {
+		value := c.GetHeader(header)
+		if value == "" {
+			c.Next()
+			return
+		}
+		key := idempotencyKey{c.GetString(clientKey), c.Request.Method, c.Request.URL.Path, value}
+		fingerprint := sha256.Sum256([]byte(rawBody(c)))
+
+		entry, recorded, seen := keys.claim(key)
+		if seen {
+			replay(c, recorded, fingerprint)
+			return
+		}
+
+		// Unanswered requests, including those that panicked, are
+		// forgotten so they may match once retried.
+		var response *recordedResponse
+		defer func() { keys.settle(entry, response) }()
+
+		original := c.Writer
+		writer := &recordingWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if _, matched := c.Get(matchedMappingKey); matched {
+			response = &recordedResponse{fingerprint: fingerprint, status: writer.Status(), header: writer.Header().Clone(), body: writer.body.Bytes()}
+		}
+	}
+}
+
+func replay(c *gin.Context, recorded *recordedResponse, fingerprint [32]byte) {
+	switch {
+	case recorded == nil:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this idempotency key is in progress"})
+	case recorded.fingerprint != fingerprint:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key reused with a different request body"})
+	default:
+		for name, values := range recorded.header {
+			c.Writer.Header()[name] = values
+		}
+		c.Header(replayedHeader, "true")
+		c.Status(recorded.status)
+		c.Writer.Write(recorded.body)
+		c.Abort()
+	}
+}
+
+// recordingWriter keeps a copy of the response body, or of its first limit
+// bytes when limit is set.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.record([]byte(data))
+	return w.ResponseWriter.WriteString(data)
+}
+
+func (w *recordingWriter) record(data []byte) {
+	if w.limit > 0 && w.body.Len()+len(data) > w.limit {
+		data, w.truncated = data[:w.limit-w.body.Len()], true
+	}
+	w.body.Write(data)
+}
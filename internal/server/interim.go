@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const connectionWriterKey = "doppelganger.connectionWriter"
+
+// ConnectionWriter remembers the writer of the request before other
+// middlewares wrap it, so interim responses reach the client even when
+// the final one is held back.
+func ConnectionWriter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(connectionWriterKey, c.Writer)
+		c.Next()
+	}
+}
+
+// sendInterim writes the 1xx responses of a mapping. Their headers stay
+// set for the final response, as early hints usually repeat in it.
+func sendInterim(c *gin.Context, interims []config.Interim) {
+	writer := c.MustGet(connectionWriterKey).(interface{ Unwrap() http.ResponseWriter }).Unwrap()
+	for _, interim := range interims {
+		delay(c, time.Duration(interim.Delay)*time.Millisecond)
+		for name, value := range interim.Headers {
+			writer.Header().Set(name, value)
+		}
+		writer.WriteHeader(interim.Code)
+	}
+}
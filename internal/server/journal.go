@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	matchedEndpointKey = "doppelganger.matchedEndpoint"
+	matchedMappingKey  = "doppelganger.matchedMapping"
+)
+
+// Journal hands every handled request to the recorder once the response
+// has been produced.
+func Journal(recorder journal.Recorder, serverName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		received := time.Now()
+		writer := &recordingWriter{ResponseWriter: c.Writer, limit: journal.MaxResponseBody}
+		c.Writer = writer
+
+		c.Next()
+
+		mapping, matched := c.Get(matchedMappingKey)
+		entry := journal.Entry{
+			Time:     received,
+			Server:   serverName,
+			Remote:   c.Request.RemoteAddr,
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			Query:    c.Request.URL.RawQuery,
+			Headers:  c.Request.Header.Clone(),
+			Body:     string(body),
+			Status:   c.Writer.Status(),
+			Matched:  matched,
+			Endpoint: c.GetString(matchedEndpointKey),
+		}
+		if matched {
+			entry.Mapping = mapping.(string)
+		}
+		entry.Response = &journal.Response{
+			Headers:   writer.Header().Clone(),
+			Body:      writer.body.String(),
+			Truncated: writer.truncated,
+			Time:      float64(time.Since(received)) / float64(time.Millisecond),
+		}
+		if closest, ok := c.Get(nearMissKey); ok {
+			entry.NearMiss = closest.(*journal.NearMiss)
+		}
+		redactEntry(redaction(c), &entry)
+		recorder.Record(entry)
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/kv"
+	"github.com/gin-gonic/gin"
+)
+
+const kvKey = "doppelganger.kv"
+
+// KV lets templates and expressions read the key-value store.
+func KV(store *kv.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(kvKey, store)
+		c.Next()
+	}
+}
+
+func kvFetcher(c *gin.Context) func(string) (any, bool) {
+	store, ok := c.Get(kvKey)
+	if !ok {
+		return nil
+	}
+	return store.(*kv.Store).Get
+}
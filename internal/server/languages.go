@@ -0,0 +1,77 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+type languagePreference struct {
+	tag     string
+	quality float64
+}
+
+// selectLanguage picks the content variant best matching Accept-Language,
+// trying exact tags first and then their base language, and falls back to
+// the default content.
+func selectLanguage(c *gin.Context, content config.Content) config.Content {
+	if len(content.Languages) == 0 {
+		return content
+	}
+	c.Header("Vary", "Accept-Language")
+
+	for _, preference := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if preference.tag == "*" {
+			break
+		}
+		if tag, variant, ok := findLanguage(content.Languages, preference.tag); ok {
+			c.Header("Content-Language", tag)
+			return variant
+		}
+	}
+	return content
+}
+
+func findLanguage(languages map[string]config.Content, wanted string) (string, config.Content, bool) {
+	for tag, variant := range languages {
+		if strings.EqualFold(tag, wanted) {
+			return tag, variant, true
+		}
+	}
+
+	base, _, _ := strings.Cut(wanted, "-")
+	for tag, variant := range languages {
+		if strings.EqualFold(tag, base) {
+			return tag, variant, true
+		}
+	}
+	return "", config.Content{}, false
+}
+
+func parseAcceptLanguage(header string) []languagePreference {
+	var preferences []languagePreference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			preferences = append(preferences, languagePreference{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	return preferences
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	intentionalDelayKey = "doppelganger.intentionalDelay"
+	unroutedEndpoint    = "unrouted"
+)
+
+// Metrics records how long each endpoint took to handle a request,
+// leaving out delays configured on purpose, and warns about the ones
+// slower than slowThreshold (zero disables the warning).
+func Metrics(registry *metrics.Registry, serverName string, slowThreshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		elapsed := time.Since(start) - c.GetDuration(intentionalDelayKey)
+		endpoint := c.GetString(matchedEndpointKey)
+		if endpoint == "" {
+			endpoint = unroutedEndpoint
+		}
+
+		registry.Observe(serverName, endpoint, elapsed)
+		if slowThreshold > 0 && elapsed > slowThreshold {
+			log.Printf("Slow request on server %s endpoint %s: %s %s took %s\n", serverName, endpoint, c.Request.Method, c.Request.URL.Path, elapsed)
+		}
+	}
+}
+
+func delay(c *gin.Context, duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	c.Set(intentionalDelayKey, c.GetDuration(intentionalDelayKey)+duration)
+	time.Sleep(duration)
+}
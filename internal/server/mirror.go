@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// maxMirrored caps the shadow requests in flight, the others are dropped
+// rather than piling up behind a slow service.
+const maxMirrored = 64
+
+// hopHeaders only make sense on the connection they were received on.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length"}
+
+// Mirror sends a copy of every request to the mirror once it was answered,
+// without waiting for the real service. When comparing, its responses
+// that differ from the mock ones are logged.
+func Mirror(mirror *config.Mirror) gin.HandlerFunc {
+	client := &http.Client{Timeout: time.Duration(mirror.Timeout) * time.Millisecond}
+	slots := make(chan struct{}, maxMirrored)
+
+	return func(c *gin.Context) {
+		body := rawBody(c)
+		target := *mirror.Target
+		target.Path = strings.TrimSuffix(target.Path, "/") + c.Request.URL.Path
+		target.RawQuery = c.Request.URL.RawQuery
+		header := c.Request.Header.Clone()
+		for _, name := range hopHeaders {
+			header.Del(name)
+		}
+
+		var writer *recordingWriter
+		if mirror.Compare {
+			writer = &recordingWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+		}
+		c.Next()
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			log.Println("Dropping shadow request " + c.Request.Method + " " + c.Request.URL.Path + ": too many in flight")
+			return
+		}
+
+		// The context is reused once the handler returns.
+		method, path := c.Request.Method, c.Request.URL.Path
+		var status int
+		var mock []byte
+		if writer != nil {
+			status, mock = writer.Status(), writer.body.Bytes()
+		}
+		go func() {
+			defer func() { <-slots }()
+
+			request, err := http.NewRequest(method, target.String(), strings.NewReader(body))
+			if err != nil {
+				log.Println("Error mirroring " + method + " " + path + ": " + err.Error())
+				return
+			}
+			request.Header = header
+			resp, err := client.Do(request)
+			if err != nil {
+				log.Println("Error mirroring " + method + " " + path + ": " + err.Error())
+				return
+			}
+			defer resp.Body.Close()
+			upstream, err := io.ReadAll(resp.Body)
+			if err != nil || writer == nil {
+				return
+			}
+
+			if status != resp.StatusCode {
+				log.Printf("Shadow %s %s: mock answered %d, real service %d", method, path, status, resp.StatusCode)
+			} else if !sameBody(mock, upstream) {
+				log.Printf("Shadow %s %s: mock and real service bodies differ", method, path)
+			}
+		}()
+	}
+}
+
+// sameBody compares JSON bodies by value, others byte for byte.
+func sameBody(mock, real []byte) bool {
+	var mockValue, realValue any
+	if json.Unmarshal(mock, &mockValue) == nil && json.Unmarshal(real, &realValue) == nil {
+		return reflect.DeepEqual(mockValue, realValue)
+	}
+	return bytes.Equal(mock, real)
+}
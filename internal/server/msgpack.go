@@ -0,0 +1,31 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const msgpackContentType = "application/msgpack"
+
+// serveMsgpack encodes JSON-like data, where every number is a float64, so
+// whole numbers are sent as integers.
+func serveMsgpack(c *gin.Context, code int, data any, signature *config.Signature) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.UseCompactInts(true)
+	encoder.UseCompactFloats(true)
+	if err := encoder.Encode(data); err != nil {
+		log.Println("Error encoding MessagePack: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if signature != nil {
+		c.Header(signature.Header, signature.Sign(buffer.Bytes()))
+	}
+	c.Data(code, msgpackContentType, buffer.Bytes())
+}
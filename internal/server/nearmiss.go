@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+	"github.com/gin-gonic/gin"
+)
+
+const nearMissKey = "doppelganger.nearMiss"
+
+// nearMiss finds the mapping of the endpoint with the most params holding
+// for the request and explains why the others did not.
+func nearMiss(c *gin.Context, body map[string]any, endpoint config.Endpoint) *journal.NearMiss {
+	var closest *journal.NearMiss
+	for i, mapping := range endpoint.Mappings {
+		fetchers := buildFetchers(c, body)
+		candidate := &journal.NearMiss{Mapping: mapping.Label(i), Disabled: !enabled(c, endpoint, mapping, i)}
+		for j, param := range mapping.Params {
+			if param.Evaluate(fetchers).(bool) {
+				candidate.Held++
+				continue
+			}
+			expected, _ := json.Marshal(param)
+			candidate.Failed = append(candidate.Failed, journal.Mismatch{
+				Param:    j,
+				Expected: expected,
+				Actual:   expressions.Explain(param, fetchers),
+			})
+		}
+		if !inState(c, mapping) {
+			candidate.State = fmt.Sprintf("scenario %s is in state %s, the mapping needs %s",
+				mapping.Scenario, store(c).State(c.GetString(clientKey), mapping.Scenario), mapping.RequiredState)
+		}
+
+		if closest == nil || candidate.Held > closest.Held ||
+			candidate.Held == closest.Held && len(candidate.Failed) < len(closest.Failed) {
+			closest = candidate
+		}
+	}
+	return closest
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/dsa-ferreira/doppelganger/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// maxValidatedBody is the biggest response body checked against the spec,
+// so big files are not kept in memory just to be skipped by the validator.
+const maxValidatedBody = 10 << 20
+
+// OpenAPI answers requests left unanswered, either without an endpoint or
+// without a matching mapping, with a response made up from the spec.
+// Responses of mappings are checked against the spec and mismatches are
+// logged, or answered with a 500 when strict.
+func OpenAPI(spec *openapi.Spec, strict bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &contractWriter{ResponseWriter: original, hold: strict, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if _, matched := c.Get(matchedMappingKey); matched {
+			checkContract(c, spec, writer, strict)
+			return
+		}
+
+		if writer.Written() {
+			writer.flush()
+			return
+		}
+		status, body, found := spec.Synthesize(c.Request)
+		if !found {
+			writer.flush()
+			return
+		}
+		if c.GetBool(verboseKey) {
+			log.Printf("Synthesized response for %s %s from the OpenAPI spec\n", c.Request.Method, c.Request.URL.Path)
+		}
+		if body == nil {
+			c.Status(status)
+			return
+		}
+		c.JSON(status, body)
+	}
+}
+
+func checkContract(c *gin.Context, spec *openapi.Spec, writer *contractWriter, strict bool) {
+	if writer.truncated {
+		writer.flush()
+		return
+	}
+
+	err := spec.Validate(c.Request, writer.Status(), writer.Header(), writer.body.Bytes())
+	if err != nil {
+		log.Printf("Contract drift on %s %s (mapping %s): %s\n", c.Request.Method, c.Request.URL.Path, c.GetString(matchedMappingKey), err)
+	}
+	if err != nil && strict {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "response does not match the OpenAPI spec: " + err.Error()})
+		return
+	}
+	writer.flush()
+}
+
+// contractWriter keeps a copy of the response body for validation. When
+// holding, nothing reaches the client until flush is called.
+type contractWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+	hold      bool
+	status    int
+	written   bool
+}
+
+func (w *contractWriter) WriteHeader(code int) {
+	if w.hold {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *contractWriter) WriteHeaderNow() {
+	if w.hold {
+		w.written = true
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *contractWriter) Write(data []byte) (int, error) {
+	if w.hold {
+		w.written = true
+		return w.body.Write(data)
+	}
+	if w.body.Len()+len(data) > maxValidatedBody {
+		w.truncated = true
+	} else {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *contractWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *contractWriter) Status() int {
+	if w.hold {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *contractWriter) Written() bool {
+	if w.hold {
+		return w.written
+	}
+	return w.ResponseWriter.Written()
+}
+
+// flush sends a held response.
+func (w *contractWriter) flush() {
+	if !w.hold {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxConcurrentRequests simulates an overloaded upstream: past max
+// requests in flight and maxQueued waiting ones, requests are answered
+// with a 503 and a Retry-After of retryAfter seconds, 1 when not set.
+func MaxConcurrentRequests(max int, maxQueued int, retryAfter int) gin.HandlerFunc {
+	if retryAfter <= 0 {
+		retryAfter = 1
+	}
+	slots := make(chan struct{}, max)
+	var queued atomic.Int64
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			if queued.Add(1) > int64(maxQueued) {
+				queued.Add(-1)
+				c.Header("Retry-After", strconv.Itoa(retryAfter))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "too many concurrent requests"})
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+				queued.Add(-1)
+			case <-c.Request.Context().Done():
+				queued.Add(-1)
+				c.Abort()
+				return
+			}
+		}
+
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// servePage answers with one page of a dataset, along with the total count
+// and links to the neighbouring pages.
+func servePage(c *gin.Context, code int, page config.DataPage) {
+	items, err := pageItems(page)
+	if err != nil {
+		log.Println("Error loading dataset: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	items = filterItems(c, items, page.Filters)
+
+	number := queryInt(c, page.PageParam, 1)
+	size := queryInt(c, page.SizeParam, page.DefaultSize)
+	if page.MaxSize > 0 {
+		size = min(size, page.MaxSize)
+	}
+
+	total := len(items)
+	pages := (total + size - 1) / size
+	start := min((number-1)*size, total)
+	end := min(start+size, total)
+
+	response := gin.H{
+		"items":      items[start:end],
+		"page":       number,
+		"size":       size,
+		"total":      total,
+		"totalPages": pages,
+		"next":       nil,
+		"prev":       nil,
+	}
+	if number < pages {
+		response["next"] = pageLink(c, page.PageParam, number+1)
+	}
+	if number > 1 {
+		response["prev"] = pageLink(c, page.PageParam, min(number-1, max(pages, 1)))
+	}
+	c.JSON(code, response)
+}
+
+func filterItems(c *gin.Context, items []any, filters map[string]string) []any {
+	filtered := make([]any, 0, len(items))
+	for _, item := range items {
+		if matchesFilters(c, item, filters) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func matchesFilters(c *gin.Context, item any, filters map[string]string) bool {
+	for param, field := range filters {
+		wanted, ok := c.GetQueryArray(param)
+		if !ok {
+			continue
+		}
+		value, found := lookupField(item, field)
+		if !found || !slices.Contains(wanted, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupField follows a dotted path through nested objects.
+func lookupField(item any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if item, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return item, true
+}
+
+func pageItems(page config.DataPage) ([]any, error) {
+	if page.File == "" {
+		return page.Items, nil
+	}
+
+	data, err := os.ReadFile(page.File)
+	if err != nil {
+		return nil, err
+	}
+	var items []any
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// queryInt reads a positive number from the query, falling back to the
+// default when it is missing or invalid.
+func queryInt(c *gin.Context, name string, fallback int) int {
+	value, err := strconv.Atoi(c.Query(name))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
+
+func pageLink(c *gin.Context, param string, number int) string {
+	link := *c.Request.URL
+	query := link.Query()
+	query.Set(param, strconv.Itoa(number))
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveProxy forwards the request to the upstream, joining its path with
+// the request's, and applies the rewrite rules to the response.
+func serveProxy(c *gin.Context, data config.DataProxy) {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(data.Target)
+			r.SetXForwarded()
+			if data.Rewrite != nil && len(data.Rewrite.Fields) > 0 {
+				// Let the transport decompress the body for us.
+				r.Out.Header.Del("Accept-Encoding")
+			}
+		},
+	}
+	if data.Rewrite != nil {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			return rewriteResponse(resp, data.Rewrite)
+		}
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+func rewriteResponse(resp *http.Response, rewrite *config.ProxyRewrite) error {
+	if rewrite.Status != 0 {
+		resp.StatusCode = rewrite.Status
+	}
+	for name, value := range rewrite.Headers {
+		resp.Header.Set(name, value)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if len(rewrite.Fields) == 0 || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		// Not JSON after all, leave it alone.
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return nil
+	}
+	for path, value := range rewrite.Fields {
+		document = setField(document, path, value)
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// setField gives the dotted path of a JSON document the value, creating the
+// objects missing along the way. Paths through other values are ignored.
+func setField(document any, path string, value any) any {
+	if document == nil {
+		document = map[string]any{}
+	}
+	object, ok := document.(map[string]any)
+	if !ok {
+		return document
+	}
+	key, rest, nested := strings.Cut(path, ".")
+	if nested {
+		object[key] = setField(object[key], rest, value)
+	} else {
+		object[key] = value
+	}
+	return object
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveQuery answers with the records of a dataset meeting the conditions,
+// sorted, limited and reduced to the selected fields.
+func serveQuery(c *gin.Context, code int, body map[string]any, query config.DataQuery) {
+	fetchers := buildFetchers(c, body)
+	var conditions []condition
+	for _, where := range query.Where {
+		if wanted := conditionValues(where.Value.Evaluate(fetchers)); len(wanted) > 0 {
+			conditions = append(conditions, condition{field: where.Field, op: where.Op, wanted: wanted})
+		}
+	}
+
+	records := []map[string]any{}
+	for _, record := range query.Records {
+		if meetsAll(record, conditions) {
+			records = append(records, record)
+		}
+	}
+
+	sort := query.Sort
+	if requested := c.Query(query.SortParam); query.SortParam != "" && requested != "" {
+		sort = requested
+	}
+	if sort != "" {
+		field, descending := strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+		slices.SortStableFunc(records, func(a, b map[string]any) int {
+			left, _ := lookupField(a, field)
+			right, _ := lookupField(b, field)
+			if descending {
+				return compareValues(right, left)
+			}
+			return compareValues(left, right)
+		})
+	}
+
+	limit := query.Limit
+	if requested, err := strconv.Atoi(c.Query(query.LimitParam)); query.LimitParam != "" && err == nil && requested >= 0 {
+		if limit == 0 || requested < limit {
+			limit = requested
+		}
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	if len(query.Select) > 0 {
+		for i, record := range records {
+			selected := make(map[string]any, len(query.Select))
+			for _, field := range query.Select {
+				if value, found := lookupField(record, field); found {
+					selected[field] = value
+				}
+			}
+			records[i] = selected
+		}
+	}
+
+	c.JSON(code, records)
+}
+
+type condition struct {
+	field  string
+	op     string
+	wanted []string
+}
+
+// conditionValues reads what an expression gave as the values a field may
+// be compared with, none when it is empty.
+func conditionValues(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		return slices.DeleteFunc(slices.Clone(v), func(s string) bool { return s == "" })
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+// meetsAll tells whether a record meets every condition. A condition
+// given several values holds when any of them does, except for ne which
+// must hold for them all.
+func meetsAll(record map[string]any, conditions []condition) bool {
+	for _, cond := range conditions {
+		value, found := lookupField(record, cond.field)
+		if !found {
+			return false
+		}
+		met := cond.op == "ne"
+		for _, wanted := range cond.wanted {
+			if cond.op == "ne" {
+				met = met && meets(value, cond.op, wanted)
+			} else {
+				met = met || meets(value, cond.op, wanted)
+			}
+		}
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
+func meets(value any, op string, wanted string) bool {
+	switch op {
+	case "eq":
+		return fmt.Sprint(value) == wanted
+	case "ne":
+		return fmt.Sprint(value) != wanted
+	case "contains":
+		return strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(wanted))
+	}
+
+	comparison := compareValues(value, wanted)
+	switch op {
+	case "lt":
+		return comparison < 0
+	case "lte":
+		return comparison <= 0
+	case "gt":
+		return comparison > 0
+	}
+	return comparison >= 0
+}
+
+// compareValues compares as numbers when both values are numeric, as text
+// otherwise. Missing values come first.
+func compareValues(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	left, right := fmt.Sprint(a), fmt.Sprint(b)
+	x, errX := strconv.ParseFloat(left, 64)
+	y, errY := strconv.ParseFloat(right, 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(left, right)
+}
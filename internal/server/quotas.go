@@ -0,0 +1,51 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/quotas"
+	"github.com/gin-gonic/gin"
+)
+
+const quotasKey = "doppelganger.quotas"
+
+type quotaCounter struct {
+	store  *quotas.Store
+	server string
+}
+
+// Quotas counts the requests answered by mappings with a quota, partitioned
+// by the value of clientKey when the server has one.
+func Quotas(store *quotas.Store, serverName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(quotasKey, quotaCounter{store: store, server: serverName})
+		c.Next()
+	}
+}
+
+// withinQuota counts a request answered by the mapping against its quota,
+// returning the mapping to answer with: the mapping itself, or its
+// exhausted response once the quota is used up.
+func withinQuota(c *gin.Context, endpoint config.Endpoint, mapping config.Mapping, index int) config.Mapping {
+	quota := mapping.Quota
+	if quota == nil {
+		return mapping
+	}
+	name := quota.Name
+	if name == "" {
+		name = endpoint.Label() + "#" + mapping.Label(index)
+	}
+
+	counter := c.MustGet(quotasKey).(quotaCounter)
+	ok, renews := counter.store.Take(counter.server, c.GetString(clientKey), name, quota.Limit, time.Duration(quota.Window)*time.Millisecond)
+	if ok {
+		return mapping
+	}
+	if !renews.IsZero() {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(renews).Seconds()))))
+	}
+	return config.Mapping{RespCode: quota.Code, Content: quota.Content}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveRaw writes the payload, status line and headers included, straight
+// to the connection and closes it, so responses can be as malformed as a
+// test needs.
+func serveRaw(c *gin.Context, payload config.Payload) {
+	if c.Request.ProtoMajor != 1 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "RAW content needs an HTTP/1.x connection"})
+		return
+	}
+
+	conn, buffered, err := c.MustGet(connectionWriterKey).(gin.ResponseWriter).Hijack()
+	if err != nil {
+		log.Println("Error taking over the connection: " + err.Error())
+		return
+	}
+	defer conn.Close()
+
+	buffered.Write(payload.Bytes)
+	if err := buffered.Flush(); err != nil && c.GetBool(verboseKey) {
+		log.Println("Error writing RAW content: " + err.Error())
+	}
+}
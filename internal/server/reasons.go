@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const reasonKey = "doppelganger.reason"
+
+// ReasonPhrases lets mappings replace the reason phrase of the status
+// line, which net/http always writes itself: the response is held back
+// and written straight to the connection, which is closed afterwards.
+func ReasonPhrases() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &reasonWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Set(reasonKey, writer)
+		c.Next()
+		if writer.reason != "" {
+			writer.send(c)
+		}
+	}
+}
+
+// usesReasons tells whether a mapping of the server sets a reason phrase.
+func usesReasons(configuration *config.Configuration) bool {
+	for _, endpoint := range configuration.Endpoints {
+		for _, mapping := range endpoint.Mappings {
+			if mapping.Reason != "" {
+				return true
+			}
+			for _, variant := range mapping.Variants {
+				if variant.Reason != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// holdForReason makes the response of the request be sent with reason as
+// its reason phrase. HTTP/2 and HTTP/3 have none, so it is left out there.
+func holdForReason(c *gin.Context, reason string) {
+	writer, ok := c.Get(reasonKey)
+	if reason == "" || !ok || c.Request.ProtoMajor != 1 {
+		return
+	}
+	writer.(*reasonWriter).reason = reason
+}
+
+// reasonWriter buffers the body once a reason is set, leaving the status
+// and headers to the wrapped writer, which writes nothing until asked to.
+type reasonWriter struct {
+	gin.ResponseWriter
+	reason string
+	body   bytes.Buffer
+}
+
+func (w *reasonWriter) Write(data []byte) (int, error) {
+	if w.reason == "" {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *reasonWriter) WriteString(s string) (int, error) {
+	if w.reason == "" {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+func (w *reasonWriter) WriteHeaderNow() {
+	if w.reason == "" {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *reasonWriter) Flush() {
+	if w.reason == "" {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// send writes the held back response with its reason phrase.
+func (w *reasonWriter) send(c *gin.Context) {
+	conn, buffered, err := w.ResponseWriter.Hijack()
+	if err != nil {
+		log.Println("Error taking over the connection: " + err.Error())
+		return
+	}
+	defer conn.Close()
+
+	code, header := w.Status(), w.Header().Clone()
+	body := w.body.Bytes()
+	if config.Bodiless(code) {
+		body = nil
+		header.Del("Content-Length")
+	} else if header.Get("Content-Length") == "" {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	if c.Request.Method == http.MethodHead {
+		body = nil
+	}
+	if header.Get("Date") == "" {
+		header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	header.Set("Connection", "close")
+
+	fmt.Fprintf(buffered, "HTTP/%d.%d %03d %s\r\n", c.Request.ProtoMajor, c.Request.ProtoMinor, code, w.reason)
+	header.Write(buffered)
+	buffered.WriteString("\r\n")
+	buffered.Write(body)
+	if err := buffered.Flush(); err != nil && c.GetBool(verboseKey) {
+		log.Println("Error writing response: " + err.Error())
+	}
+}
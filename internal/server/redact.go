@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	redactKey = "doppelganger.redact"
+	redacted  = "[REDACTED]"
+)
+
+// Redaction hands the redaction rules of the server to the journal and the
+// request logger. Endpoints add their own rules once the request is routed.
+func Redaction(rules *config.Redact) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(redactKey, rules)
+		c.Next()
+	}
+}
+
+// redactEndpoint adds the rules of the endpoint a request was routed to.
+func redactEndpoint(c *gin.Context, endpoint config.Endpoint) {
+	if endpoint.Redact == nil {
+		return
+	}
+	rules := *endpoint.Redact
+	if server := redaction(c); server != nil {
+		rules = config.Redact{
+			Headers: append(slices.Clone(server.Headers), rules.Headers...),
+			Fields:  append(slices.Clone(server.Fields), rules.Fields...),
+		}
+	}
+	c.Set(redactKey, &rules)
+}
+
+func redaction(c *gin.Context) *config.Redact {
+	rules, _ := c.Get(redactKey)
+	redact, _ := rules.(*config.Redact)
+	return redact
+}
+
+// redactEntry masks the secrets of a journal entry in place.
+func redactEntry(rules *config.Redact, entry *journal.Entry) {
+	if rules == nil {
+		return
+	}
+	contentType := http.Header(entry.Headers).Get("Content-Type")
+	entry.Headers = redactHeaders(rules, entry.Headers)
+	entry.Query = redactQuery(rules, entry.Query)
+	entry.Body = redactBody(rules, entry.Body, contentType)
+	if entry.Response != nil {
+		contentType := http.Header(entry.Response.Headers).Get("Content-Type")
+		entry.Response.Headers = redactHeaders(rules, entry.Response.Headers)
+		entry.Response.Body = redactBody(rules, entry.Response.Body, contentType)
+	}
+	if entry.NearMiss != nil {
+		for i := range entry.NearMiss.Failed {
+			for j := range entry.NearMiss.Failed[i].Actual {
+				redactObserved(rules, &entry.NearMiss.Failed[i].Actual[j])
+			}
+		}
+	}
+}
+
+// accessLog formats request lines like gin.Logger, masking the redacted
+// query params.
+func accessLog(param gin.LogFormatterParams) string {
+	query := param.Request.URL.RawQuery
+	if rules, _ := param.Keys[redactKey].(*config.Redact); rules != nil && query != "" {
+		param.Path = strings.TrimSuffix(param.Path, "?"+query) + "?" + redactQuery(rules, query)
+	}
+
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor, methodColor, resetColor = param.StatusCodeColor(), param.MethodColor(), param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+func redactHeaders(rules *config.Redact, headers map[string][]string) map[string][]string {
+	for _, name := range rules.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if values, ok := headers[name]; ok {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = redacted
+			}
+			headers[name] = masked
+		}
+	}
+	return headers
+}
+
+func redactQuery(rules *config.Redact, query string) string {
+	if query == "" || len(rules.Fields) == 0 {
+		return query
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil || !redactValues(rules, values) {
+		return query
+	}
+	return values.Encode()
+}
+
+// redactValues masks the top level fields among form values or query
+// params, telling whether there were any.
+func redactValues(rules *config.Redact, values url.Values) bool {
+	found := false
+	for _, field := range rules.Fields {
+		if items, ok := values[field]; ok {
+			for i := range items {
+				items[i] = redacted
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// redactBody masks the fields of JSON and form bodies. Other bodies, and
+// bodies that do not parse, are kept as they are.
+func redactBody(rules *config.Redact, body string, contentType string) string {
+	if body == "" || len(rules.Fields) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return redactQuery(rules, body)
+	case strings.HasSuffix(mediaType, "json"):
+		decoder := json.NewDecoder(strings.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return body
+		}
+		for _, field := range rules.Fields {
+			value = redactField(value, strings.Split(field, "."))
+		}
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return body
+		}
+		return strings.TrimSuffix(buffer.String(), "\n")
+	default:
+		return body
+	}
+}
+
+// redactField masks the value at path, looking into every item of the
+// arrays on the way.
+func redactField(value any, path []string) any {
+	switch value := value.(type) {
+	case map[string]any:
+		child, ok := value[path[0]]
+		if !ok {
+			return value
+		}
+		if len(path) == 1 {
+			value[path[0]] = redacted
+		} else {
+			value[path[0]] = redactField(child, path[1:])
+		}
+		return value
+	case []any:
+		for i := range value {
+			value[i] = redactField(value[i], path)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// redactObserved masks the values near misses report for redacted headers
+// and fields.
+func redactObserved(rules *config.Redact, observed *expressions.Observed) {
+	var expression struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+	if err := json.Unmarshal(observed.Expression, &expression); err != nil || observed.Value == nil {
+		return
+	}
+	header := func(name string) bool {
+		return slices.ContainsFunc(rules.Headers, func(redacted string) bool { return strings.EqualFold(redacted, name) })
+	}
+	switch expression.Type {
+	case "HEADER", "HEADER_ARRAY":
+		if !header(expression.ID) {
+			return
+		}
+	case "COOKIE":
+		if !header("Cookie") {
+			return
+		}
+	case "BODY", "BODY_ARRAY", "FORM_ARRAY", "QUERY", "QUERY_ARRAY":
+		if !slices.Contains(rules.Fields, expression.ID) {
+			return
+		}
+	case "RAW_BODY":
+		if len(rules.Fields) == 0 {
+			return
+		}
+	default:
+		return
+	}
+	observed.Value = redacted
+}
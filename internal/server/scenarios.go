@@ -0,0 +1,46 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/scenarios"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scenariosKey = "doppelganger.scenarios"
+	clientKey    = "doppelganger.client"
+)
+
+// Scenarios hands the scenario states to the mappings, partitioned by the
+// value of clientKey when the server has one.
+func Scenarios(store *scenarios.Store, key expressions.Expression) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(scenariosKey, store)
+		if key != nil {
+			c.Set(clientKey, key.Evaluate(buildFetchers(c, nil)).(string))
+		}
+		c.Next()
+	}
+}
+
+// inState tells whether the scenario of a mapping allows it to answer.
+func inState(c *gin.Context, mapping config.Mapping) bool {
+	if mapping.Scenario == "" || mapping.RequiredState == "" {
+		return true
+	}
+	return store(c).State(c.GetString(clientKey), mapping.Scenario) == mapping.RequiredState
+}
+
+// advance moves the scenario of a mapping along, failing when a concurrent
+// request already did.
+func advance(c *gin.Context, mapping config.Mapping) bool {
+	if mapping.Scenario == "" {
+		return true
+	}
+	return store(c).Advance(c.GetString(clientKey), mapping.Scenario, mapping.RequiredState, mapping.NewState)
+}
+
+func store(c *gin.Context) *scenarios.Store {
+	return c.MustGet(scenariosKey).(*scenarios.Store)
+}
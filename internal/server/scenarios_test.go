@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dsa-ferreira/doppelganger/pkg/doppelgangertest"
+)
+
+const steps = 20
+
+// scenarioConfig chains steps mappings through a scenario, listed last step
+// first so the order of the mappings cannot keep the steps in order.
+func scenarioConfig() []byte {
+	var mappings []string
+	for step := steps; step >= 1; step-- {
+		required, next := fmt.Sprintf("step %d", step), fmt.Sprintf("step %d", step+1)
+		if step == 1 {
+			required = "Started"
+		}
+		if step == steps {
+			next = "Finished"
+		}
+		mappings = append(mappings, fmt.Sprintf(`{"scenario": "counter", "requiredState": %q, "newState": %q, "content": {"data": {"step": %d}}}`, required, next, step))
+	}
+	mappings = append(mappings, `{"content": {"data": {"step": 0}}}`)
+	return []byte(`{"servers": [{"port": 8081, "endpoint": [{"path": "/next", "verb": "GET", "mappings": [` + strings.Join(mappings, ",") + `]}]}]}`)
+}
+
+func next(t *testing.T, server *doppelgangertest.Server) int {
+	resp, err := http.Get(server.URL + "/next")
+	if err != nil {
+		t.Error(err)
+		return -1
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+		return -1
+	}
+	var answer struct {
+		Step int `json:"step"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil {
+		t.Errorf("decoding %s: %s", body, err)
+		return -1
+	}
+	return answer.Step
+}
+
+func TestScenarioConcurrentRequests(t *testing.T) {
+	server := doppelgangertest.NewServerFromJSON(t, scenarioConfig())
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		served []int
+	)
+	for range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			step := next(t, server)
+			mu.Lock()
+			served = append(served, step)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, step := range served {
+		counts[step]++
+	}
+	for step := 1; step <= steps; step++ {
+		if counts[step] != 1 {
+			t.Errorf("step %d served %d times, want once; served %v", step, counts[step], served)
+		}
+	}
+
+	// Every step requires the state the previous one left, so serving each
+	// exactly once means they were served in order, leaving the scenario
+	// finished.
+	if step := next(t, server); step != 0 {
+		t.Errorf("after the scenario finished got step %d, want the fallback", step)
+	}
+	doppelgangertest.AssertReceived(t, server, doppelgangertest.Matcher{Method: "GET", Path: "/next"}, steps+1)
+}
+
+func TestScenarioSequentialRequests(t *testing.T) {
+	server := doppelgangertest.NewServerFromJSON(t, scenarioConfig())
+
+	for want := 1; want <= steps; want++ {
+		if step := next(t, server); step != want {
+			t.Fatalf("request %d got step %d", want, step)
+		}
+	}
+	if step := next(t, server); step != 0 {
+		t.Errorf("after the scenario finished got step %d, want the fallback", step)
+	}
+}
@@ -5,30 +5,81 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"time"
 
 	"github.com/dsa-ferreira/doppelganger/internal/config"
-	"github.com/dsa-ferreira/doppelganger/internal/expressions"
+	"github.com/dsa-ferreira/doppelganger/internal/events"
+	"github.com/dsa-ferreira/doppelganger/internal/filecache"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/kv"
+	"github.com/dsa-ferreira/doppelganger/internal/metrics"
+	"github.com/dsa-ferreira/doppelganger/internal/openapi"
+	"github.com/dsa-ferreira/doppelganger/internal/parsers"
+	"github.com/dsa-ferreira/doppelganger/internal/quotas"
+	"github.com/dsa-ferreira/doppelganger/internal/scenarios"
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+	"github.com/dsa-ferreira/doppelganger/internal/toggles"
+	"github.com/dsa-ferreira/doppelganger/internal/uploads"
+	"github.com/dsa-ferreira/doppelganger/internal/usage"
+	"github.com/dsa-ferreira/doppelganger/internal/variants"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
 	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
 )
 
 type mappers func(*gin.Engine, config.Endpoint)
 
+const (
+	verboseKey           = "doppelganger.verbose"
+	debugHeadersKey      = "doppelganger.debugHeaders"
+	bodyParserAliasesKey = "doppelganger.bodyParserAliases"
+	capturesKey          = "doppelganger.captures"
+	valuesKey            = "doppelganger.values"
+	rawBodyKey           = "doppelganger.rawBody"
+	debugRequestHeader   = "X-Doppelganger-Debug"
+	matchedHeader        = "X-Doppelganger-Matched"
+	endpointHeader       = "X-Doppelganger-Endpoint"
+	unmatchedHeaderValue = "none"
+)
+
+func DebugHeaders(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(debugHeadersKey, enabled || c.GetHeader(debugRequestHeader) == "true")
+		c.Next()
+	}
+}
+
+// BodyParserAliases lets custom media types reuse a registered body parser,
+// e.g. "application/vnd.acme.v1" parsed as "application/json".
+func BodyParserAliases(aliases map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(bodyParserAliasesKey, aliases)
+		c.Next()
+	}
+}
+
+// RequestLogger logs request bodies once handled, so the redaction rules
+// of the endpoint they were routed to apply.
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		buf, _ := io.ReadAll(c.Request.Body)
 		rdr1 := io.NopCloser(bytes.NewBuffer(buf))
 		rdr2 := io.NopCloser(bytes.NewBuffer(buf))
 
+		c.Request.Body = rdr2
+		c.Set(verboseKey, true)
+		c.Next()
+
 		body := readBody(rdr1)
+		if rules := redaction(c); rules != nil {
+			body = redactBody(rules, body, c.ContentType())
+		}
 		if body != "" {
-			fmt.Println("Request body: " + body)
+			log.Println("Request body: " + body)
 		}
-
-		c.Request.Body = rdr2
-		c.Next()
 	}
 }
 
@@ -43,23 +94,216 @@ func readBody(reader io.Reader) string {
 	return ""
 }
 
-func StartServer(configuration *config.Configuration, verbose bool) {
-	r := gin.Default()
+type Options struct {
+	Verbose       bool
+	Journal       journal.Recorder
+	Metrics       *metrics.Registry
+	SlowThreshold time.Duration
+	FileCache     *filecache.Cache
+	Events        *events.Queue
+	// OpenAPI makes up responses for requests no mapping answered and
+	// checks the others, answering mismatches with a 500 when strict.
+	OpenAPI       *openapi.Spec
+	OpenAPIStrict bool
+	Usage         *usage.Tracker
+	// Scenarios holds the scenario states, shared by servers given the
+	// same store. Each server gets its own when nil.
+	Scenarios *scenarios.Store
+	// Toggles disables mappings and endpoints at runtime.
+	Toggles *toggles.Store
+	// KV holds the values set through the admin API for templates and
+	// expressions.
+	KV *kv.Store
+	// Variants holds the variants clients were pinned to.
+	Variants *variants.Store
+	// Quotas counts the requests answered by mappings with a quota, each
+	// server gets its own when nil.
+	Quotas *quotas.Store
+	// Uploads holds the resumable upload sessions, each server gets its
+	// own when nil.
+	Uploads *uploads.Store
+	// Unmatched, when set, receives the requests no mapping answered,
+	// which are answered with a 501.
+	Unmatched journal.Recorder
+	// Quiet leaves out the access log line of every request.
+	Quiet bool
+	// AllowExec lists the commands EXEC content may run, servers using
+	// any other command fail to start.
+	AllowExec []string
+	// Sockets are those passed by systemd, the port is bound when nil.
+	Sockets *systemd.Sockets
+}
+
+// Listen binds the server's port and serves it in the background until the
+// returned closer is called, which also drops open connections.
+func Listen(configuration *config.Configuration, options Options) (io.Closer, error) {
+	var h3 *http3.Server
+	var extra []gin.HandlerFunc
+	if configuration.HTTP3 {
+		h3 = &http3.Server{Addr: fmt.Sprintf(":%d", configuration.Port), Port: configuration.Port}
+		extra = append(extra, AltSvc(h3))
+	}
 
-	if verbose {
+	r, err := newEngine(configuration, options, extra...)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := fmt.Sprintf(":%d", configuration.Port)
+	listener, err := options.Sockets.Listen(configuration.Name, configuration.Port)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Addr: addr, Handler: r.Handler(), ConnContext: countRequests}
+	if configuration.KeepAlive != nil {
+		server.SetKeepAlivesEnabled(*configuration.KeepAlive)
+	}
+	if configuration.TLS == nil {
+		go server.Serve(listener)
+		return server, nil
+	}
+
+	running := closers{server}
+	if h3 != nil {
+		h3.Handler = r
+		running = append(running, h3)
+		log.Printf("Serving experimental HTTP/3 on udp %s\n", addr)
+		go func() {
+			if err := h3.ListenAndServeTLS(configuration.TLS.CertFile, configuration.TLS.KeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Println(err)
+			}
+		}()
+	}
+	go func() {
+		if err := server.ServeTLS(listener, configuration.TLS.CertFile, configuration.TLS.KeyFile); !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+		}
+	}()
+	return running, nil
+}
+
+// closers closes every closer, returning the first error.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// NewHandler builds the routes of a server without listening, e.g. to be
+// wrapped by httptest.
+func NewHandler(configuration *config.Configuration, options Options) (http.Handler, error) {
+	return newEngine(configuration, options)
+}
+
+func newEngine(configuration *config.Configuration, options Options, extra ...gin.HandlerFunc) (*gin.Engine, error) {
+	if err := checkCommands(configuration, options.AllowExec); err != nil {
+		return nil, err
+	}
+
+	r := gin.New()
+	if !options.Quiet {
+		r.Use(gin.LoggerWithFormatter(accessLog))
+	}
+	r.Use(gin.Recovery())
+	r.Use(ConnectionWriter())
+	if usesReasons(configuration) {
+		r.Use(ReasonPhrases())
+	}
+	if options.FileCache != nil {
+		r.Use(FileCache(options.FileCache))
+	}
+
+	if configuration.Redact != nil {
+		r.Use(Redaction(configuration.Redact))
+	}
+	if options.Verbose {
 		r.Use(RequestLogger())
 	}
+	if options.Journal != nil {
+		r.Use(Journal(options.Journal, configuration.Name))
+	}
+	if options.Usage != nil {
+		r.Use(Usage(options.Usage, configuration))
+	}
+	if configuration.Mirror != nil {
+		r.Use(Mirror(configuration.Mirror))
+	}
+	if options.Events != nil {
+		r.Use(Events(options.Events, configuration.Name))
+	}
+	if options.Metrics != nil {
+		r.Use(Metrics(options.Metrics, configuration.Name, options.SlowThreshold))
+	}
+	r.Use(DebugHeaders(configuration.DebugHeaders))
+	if len(configuration.Datasets) > 0 {
+		r.Use(Datasets(configuration.Datasets))
+	}
+	if configuration.MaxConcurrentRequests > 0 {
+		r.Use(MaxConcurrentRequests(configuration.MaxConcurrentRequests, configuration.MaxQueuedRequests, configuration.RetryAfter))
+	}
+	if options.Scenarios == nil {
+		options.Scenarios = scenarios.NewStore()
+	}
+	r.Use(Scenarios(options.Scenarios, configuration.ClientKey))
+	r.Use(Started(time.Now()))
+	if options.Quotas == nil {
+		options.Quotas = quotas.NewStore()
+	}
+	r.Use(Quotas(options.Quotas, configuration.Name))
+	if options.Variants != nil {
+		r.Use(Variants(options.Variants))
+	}
+	if options.Toggles != nil {
+		r.Use(Toggles(options.Toggles, configuration.Name))
+	}
+	if options.KV != nil {
+		r.Use(KV(options.KV))
+	}
+	if options.Uploads == nil {
+		options.Uploads = uploads.NewStore()
+	}
+	r.Use(Uploads(options.Uploads))
+	if configuration.MaxRequestsPerConnection > 0 {
+		r.Use(MaxRequestsPerConnection(configuration.MaxRequestsPerConnection))
+	}
+	if configuration.Idempotency != nil {
+		idempotency := configuration.Idempotency
+		r.Use(Idempotency(idempotency.Header, time.Duration(idempotency.TTL)*time.Millisecond, idempotency.MaxKeys))
+	}
+	if options.Unmatched != nil {
+		r.Use(Strict(options.Unmatched, configuration.Name))
+	}
+	if options.OpenAPI != nil {
+		r.Use(OpenAPI(options.OpenAPI, options.OpenAPIStrict))
+	}
+	r.Use(extra...)
+	if len(configuration.BodyParsers) > 0 {
+		r.Use(BodyParserAliases(configuration.BodyParsers))
+	}
 
 	for _, endpoint := range configuration.Endpoints {
 		mapper, err := selectMap(endpoint.Verb)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(0)
+			return nil, err
 		}
 		mapper(r, endpoint)
 	}
 
-	r.Run(fmt.Sprintf(":%d", configuration.Port))
+	return r, nil
+}
+
+// AltSvc advertises the HTTP/3 listener on every response.
+func AltSvc(h3 *http3.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h3.SetQUICHeaders(c.Writer.Header())
+		c.Next()
+	}
 }
 
 func selectMap(verb string) (mappers, error) {
@@ -78,58 +322,118 @@ func selectMap(verb string) (mappers, error) {
 
 func getMap(router *gin.Engine, config config.Endpoint) {
 	router.GET(config.Path, func(c *gin.Context) {
-		mapReturns(c, nil, config.Mappings)
+		mapReturns(c, nil, config)
 	})
 }
 
 func postMap(router *gin.Engine, config config.Endpoint) {
 	router.POST(config.Path, func(c *gin.Context) {
-		mapReturnsWithBody(c, config.Mappings)
+		mapReturnsWithBody(c, config)
 	})
 }
 
 func putMap(router *gin.Engine, config config.Endpoint) {
 	router.PUT(config.Path, func(c *gin.Context) {
-		mapReturnsWithBody(c, config.Mappings)
+		mapReturnsWithBody(c, config)
 	})
 }
 
 func deleteMap(router *gin.Engine, config config.Endpoint) {
 	router.DELETE(config.Path, func(c *gin.Context) {
-		mapReturnsWithBody(c, config.Mappings)
+		mapReturnsWithBody(c, config)
 	})
 }
 
-func mapReturnsWithBody(c *gin.Context, mappings []config.Mapping) {
+func mapReturnsWithBody(c *gin.Context, endpoint config.Endpoint) {
 	contentType := c.GetHeader("Content-Type")
+	if alias, ok := c.Get(bodyParserAliasesKey); ok {
+		if target, found := alias.(map[string]string)[c.ContentType()]; found {
+			contentType = target
+		}
+	}
 
 	var body map[string]any
-	var err error
-	switch contentType {
-	case "application/json":
-		body, err = readFromJson(c)
-	case "application/x-www-form-urlencoded", "multipart/form-data":
-		body, err = readFromForm(c)
-	}
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if _, ok := parsers.Lookup(contentType); ok {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+
+		body, err = parsers.Parse(data, contentType)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if c.GetBool(verboseKey) && contentType != "" {
+		log.Println("No body parser for content type " + contentType)
 	}
 
-	mapReturns(c, body, mappings)
+	mapReturns(c, body, endpoint)
 }
 
-func mapReturns(c *gin.Context, body map[string]any, mappings []config.Mapping) {
-	for _, mapping := range mappings {
-		if allMatch(c, body, mapping.Params) {
-			buildResponse(c, mapping.RespCode, mapping.Content)
+func mapReturns(c *gin.Context, body map[string]any, endpoint config.Endpoint) {
+	c.Set(matchedEndpointKey, endpoint.Label())
+	redactEndpoint(c, endpoint)
+
+	debug := c.GetBool(debugHeadersKey)
+	if debug {
+		c.Header(endpointHeader, endpoint.Label())
+	}
+
+	for i := 0; i < len(endpoint.Mappings); i++ {
+		mapping := endpoint.Mappings[i]
+		fetchers := buildFetchers(c, body)
+		if enabled(c, endpoint, mapping, i) && inState(c, mapping) && allMatch(fetchers, mapping.Params) {
+			if !advance(c, mapping) {
+				// A concurrent request moved the scenario on after the
+				// earlier mappings were skipped for its old state: match
+				// again from the top so steps are answered in order.
+				i = -1
+				continue
+			}
+			c.Set(matchedMappingKey, mapping.Label(i))
+			c.Set(capturesKey, fetchers.Captures)
+			if len(mapping.Values) > 0 {
+				c.Set(valuesKey, evaluateValues(fetchers, mapping.Values))
+			}
+			if debug {
+				c.Header(matchedHeader, mapping.Label(i))
+			}
+			if c.GetBool(verboseKey) {
+				log.Printf("Matched mapping %s on endpoint %s\n", describe(mapping.Label(i), mapping.Name), describe(endpoint.Label(), endpoint.Name))
+			}
+			buildResponse(c, body, withinQuota(c, endpoint, withVariant(c, mapping), i))
 			return
 		}
 	}
+
+	if debug {
+		c.Header(matchedHeader, unmatchedHeaderValue)
+	}
+	closest := nearMiss(c, body, endpoint)
+	if closest != nil {
+		c.Set(nearMissKey, closest)
+	}
+	if c.GetBool(verboseKey) {
+		log.Printf("No mapping matched on endpoint %s\n", describe(endpoint.Label(), endpoint.Name))
+		if closest != nil {
+			log.Printf("Closest mapping %s failed %d params\n", closest.Mapping, len(closest.Failed))
+		}
+	}
+}
+
+func describe(label string, name string) string {
+	if name == "" {
+		return label
+	}
+	return label + " (" + name + ")"
 }
 
-func allMatch(c *gin.Context, body map[string]interface{}, params []expressions.Expression) bool {
+func allMatch(fetchers expressions.EvaluationFetchers, params []expressions.Expression) bool {
 	for _, param := range params {
-		if !param.Evaluate(expressions.EvaluationFetchers{BodyFetcher: body, QueryFetcher: c.Query, QueryArrayFetcher: c.QueryArray, ParamFetcher: c.Param}).(bool) {
+		if !param.Evaluate(fetchers).(bool) {
 			return false
 		}
 	}
@@ -137,44 +441,107 @@ func allMatch(c *gin.Context, body map[string]interface{}, params []expressions.
 	return true
 }
 
-func buildResponse(c *gin.Context, code int, content config.Content) {
-	switch content.Type {
-	case config.ContentTypeJson:
-		c.JSON(code, content.Data)
-	case config.ContentTypeFile:
-		c.Status(code)
-		c.File(content.Data.(config.DataFile).Path)
+func buildFetchers(c *gin.Context, body map[string]any) expressions.EvaluationFetchers {
+	return expressions.EvaluationFetchers{
+		BodyFetcher:        body,
+		QueryFetcher:       c.Query,
+		QueryArrayFetcher:  c.QueryArray,
+		ParamFetcher:       c.Param,
+		HeaderFetcher:      c.GetHeader,
+		HeaderArrayFetcher: c.Request.Header.Values,
+		RemoteAddrFetcher:  func() string { return c.Request.RemoteAddr },
+		HostFetcher:        func() string { return c.Request.Host },
+		TLSFetcher:         func() bool { return c.Request.TLS != nil },
+		ContentTypeFetcher: c.ContentType,
+		RawBodyFetcher:     func() string { return rawBody(c) },
+		MethodFetcher:      func() string { return c.Request.Method },
+		URLFetcher:         func() *url.URL { return c.Request.URL },
+		KVFetcher:          kvFetcher(c),
+		Captures:           make(map[string]string),
 	}
 }
 
-func readFromJson(c *gin.Context) (map[string]any, error) {
-	var body map[string]any
-	if err := c.ShouldBindJSON(&body); err != nil {
-		return nil, err
+// rawBody reads the request body once and puts it back for whoever reads
+// it next.
+func rawBody(c *gin.Context) string {
+	if body, ok := c.Get(rawBodyKey); ok {
+		return body.(string)
 	}
-	return body, nil
-}
 
-func readFromForm(c *gin.Context) (map[string]any, error) {
-	formData := c.Request.PostForm
-	if formData == nil {
-		if err := c.Request.ParseForm(); err != nil {
-			return nil, err
-		}
-		return squashFormData(c.Request.PostForm), nil
+	var body string
+	if c.Request.Body != nil {
+		data, _ := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		body = string(data)
 	}
-	return nil, errors.New("something went terribly wrong")
+	c.Set(rawBodyKey, body)
+	return body
 }
 
-func squashFormData(formData url.Values) map[string]any {
-	result := make(map[string]any)
+func buildResponse(c *gin.Context, body map[string]any, mapping config.Mapping) {
+	code, content := mapping.RespCode, selectLanguage(c, mapping.Content)
 
-	for key, values := range formData {
-		if len(values) > 1 {
-			result[key] = values // keep as []string
+	holdForReason(c, mapping.Reason)
+	sendInterim(c, mapping.Interim)
+	delay(c, time.Duration(mapping.Delay)*time.Millisecond)
+
+	if mapping.CloseConnection {
+		c.Header("Connection", "close")
+	}
+
+	if mapping.ContentLength != nil || mapping.TransferEncoding != "" {
+		c.Writer = framingWriter{ResponseWriter: c.Writer, contentLength: mapping.ContentLength, transferEncoding: mapping.TransferEncoding}
+	}
+	if mapping.Bandwidth > 0 {
+		c.Writer = &throttledWriter{ResponseWriter: c.Writer, c: c, bandwidth: mapping.Bandwidth}
+	}
+	if len(mapping.Trailers) > 0 {
+		for name := range mapping.Trailers {
+			c.Writer.Header().Add("Trailer", name)
+		}
+		c.Writer = chunkedWriter{ResponseWriter: c.Writer}
+	}
+
+	switch content.Type {
+	case config.ContentTypeJson, config.ContentTypeMsgpack:
+		data := content.Data
+		if content.CompiledTemplate != nil {
+			var err error
+			data, err = content.CompiledTemplate.Render(templateData(c, body))
+			if err != nil {
+				log.Println("Error rendering template: " + err.Error())
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if content.Type == config.ContentTypeMsgpack {
+			serveMsgpack(c, code, data, mapping.Signature)
+		} else if mapping.Signature != nil {
+			serveSignedJSON(c, code, data, mapping.Signature)
 		} else {
-			result[key] = values[0] // collapse single value
+			c.JSON(code, data)
 		}
+	case config.ContentTypeFile:
+		serveFile(c, code, content.Data.(config.DataFile))
+	case config.ContentTypePaginate:
+		servePage(c, code, content.Data.(config.DataPage))
+	case config.ContentTypeGraphQL:
+		serveGraphQL(c, code, body, content.Data.(config.DataGraphQL))
+	case config.ContentTypeUpload:
+		serveUpload(c, code, content.Data.(config.DataUpload))
+	case config.ContentTypeRaw:
+		serveRaw(c, content.Data.(config.Payload))
+	case config.ContentTypeExec:
+		serveExec(c, code, body, content.Data.(config.DataExec))
+	case config.ContentTypeProxy:
+		serveProxy(c, content.Data.(config.DataProxy))
+	case config.ContentTypeQuery:
+		serveQuery(c, code, body, content.Data.(config.DataQuery))
 	}
-	return result
+
+	for name, value := range mapping.Trailers {
+		c.Writer.Header().Set(name, value)
+	}
+
+	publish(c, body, mapping.Publish)
 }
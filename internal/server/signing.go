@@ -0,0 +1,23 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveSignedJSON encodes the body itself, as the signature must cover the
+// exact bytes sent.
+func serveSignedJSON(c *gin.Context, code int, data any, signature *config.Signature) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Println("Error encoding JSON: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header(signature.Header, signature.Sign(encoded))
+	c.Data(code, "application/json; charset=utf-8", encoded)
+}
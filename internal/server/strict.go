@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/gin-gonic/gin"
+)
+
+// Strict answers requests nothing else answered with a 501 and hands them
+// to the recorder, so unexpected traffic can fail the run.
+func Strict(recorder journal.Recorder, serverName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if _, matched := c.Get(matchedMappingKey); matched || c.Writer.Written() {
+			return
+		}
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "no mapping matched the request"})
+		entry := journal.Entry{
+			Time:     time.Now(),
+			Server:   serverName,
+			Remote:   c.Request.RemoteAddr,
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			Query:    c.Request.URL.RawQuery,
+			Status:   http.StatusNotImplemented,
+			Endpoint: c.GetString(matchedEndpointKey),
+		}
+		redactEntry(redaction(c), &entry)
+		recorder.Record(entry)
+	}
+}
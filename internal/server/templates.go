@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/kv"
+	"github.com/dsa-ferreira/doppelganger/internal/templating"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+	"github.com/gin-gonic/gin"
+)
+
+func templateData(c *gin.Context, body map[string]any) templating.Data {
+	params := make(map[string]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = param.Value
+	}
+
+	query := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		query[key] = values[0]
+	}
+
+	headers := make(map[string]string, len(c.Request.Header))
+	for key, values := range c.Request.Header {
+		headers[key] = values[0]
+	}
+
+	captures, _ := c.Get(capturesKey)
+	named, _ := captures.(map[string]string)
+	values, _ := c.Get(valuesKey)
+	evaluated, _ := values.(map[string]any)
+	var stored map[string]any
+	if store, ok := c.Get(kvKey); ok {
+		stored = store.(*kv.Store).All()
+	}
+	records, _ := c.Get(datasetsKey)
+	datasets, _ := records.(map[string][]map[string]any)
+
+	return templating.Data{
+		Method:   c.Request.Method,
+		Path:     c.Request.URL.Path,
+		Params:   params,
+		Query:    query,
+		Headers:  headers,
+		Body:     body,
+		Captures: named,
+		Values:   evaluated,
+		Datasets: datasets,
+		KV:       stored,
+	}
+}
+
+func evaluateValues(fetchers expressions.EvaluationFetchers, values map[string]expressions.Expression) map[string]any {
+	evaluated := make(map[string]any, len(values))
+	for name, value := range values {
+		evaluated[name] = value.Evaluate(fetchers)
+	}
+	return evaluated
+}
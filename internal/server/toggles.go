@@ -0,0 +1,51 @@
+package server
+
+import (
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/toggles"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	togglesKey = "doppelganger.toggles"
+	startedKey = "doppelganger.started"
+)
+
+type toggler struct {
+	store  *toggles.Store
+	server string
+}
+
+// Toggles lets mappings and endpoints be disabled at runtime, through the
+// admin API.
+func Toggles(store *toggles.Store, serverName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(togglesKey, toggler{store: store, server: serverName})
+		c.Next()
+	}
+}
+
+// Started tells mappings when the server started, for their activation
+// windows.
+func Started(at time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(startedKey, at)
+		c.Next()
+	}
+}
+
+// enabled tells whether a mapping may answer. Disabled and inactive ones
+// are skipped as if their params did not hold.
+func enabled(c *gin.Context, endpoint config.Endpoint, mapping config.Mapping, index int) bool {
+	if !mapping.Active(c.GetTime(startedKey), time.Now()) {
+		return false
+	}
+	value, ok := c.Get(togglesKey)
+	if !ok {
+		return true
+	}
+	t := value.(toggler)
+	return t.store.Enabled(t.server, endpoint.Label(), mapping.Label(index))
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	uploadsKey = "doppelganger.uploads"
+	// statusResumeIncomplete tells the client which bytes were received
+	// so far, as Google resumable uploads do.
+	statusResumeIncomplete = 308
+)
+
+func Uploads(store *uploads.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(uploadsKey, store)
+		c.Next()
+	}
+}
+
+// serveUpload starts a session on POST and stores a chunk otherwise,
+// answering with code once every byte was received.
+func serveUpload(c *gin.Context, code int, data config.DataUpload) {
+	store := c.MustGet(uploadsKey).(*uploads.Store)
+
+	if c.Request.Method == http.MethodPost {
+		total := int64(-1)
+		if length := c.GetHeader("X-Upload-Content-Length"); length != "" {
+			total, _ = strconv.ParseInt(length, 10, 64)
+		}
+		upload := store.Start(c.Request.URL.Path, total)
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+		c.JSON(code, upload)
+		return
+	}
+
+	id := c.Param(data.IDParam)
+	chunk, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	start, total, err := parseContentRange(c.GetHeader("Content-Range"), len(chunk))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := store.Write(id, start, chunk, total)
+	if errors.Is(err, uploads.ErrUnknown) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !upload.Complete {
+		if upload.Size > 0 {
+			c.Header("Range", "bytes=0-"+strconv.FormatInt(upload.Size-1, 10))
+		}
+		c.Status(statusResumeIncomplete)
+		return
+	}
+	c.JSON(code, upload)
+}
+
+// parseContentRange reads "bytes 0-99/1000", "bytes 0-99/*" or, to ask
+// for the status, "bytes */1000". Without the header the body is the
+// whole upload.
+func parseContentRange(header string, length int) (start int64, total int64, err error) {
+	if header == "" {
+		return 0, int64(length), nil
+	}
+
+	invalid := errors.New("invalid Content-Range " + header)
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, invalid
+	}
+	span, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, invalid
+	}
+
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+	}
+	if span == "*" {
+		if length != 0 {
+			return 0, 0, invalid
+		}
+		return 0, total, nil
+	}
+
+	first, last, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, invalid
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, invalid
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end-start+1 != int64(length) {
+		return 0, 0, invalid
+	}
+	return start, total, nil
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// Usage counts the requests answered by each mapping of the server.
+func Usage(tracker *usage.Tracker, configuration *config.Configuration) gin.HandlerFunc {
+	for _, endpoint := range configuration.Endpoints {
+		for i, mapping := range endpoint.Mappings {
+			tracker.Register(configuration.Name, endpoint.Label(), mapping.Label(i))
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		if mapping, matched := c.Get(matchedMappingKey); matched {
+			tracker.Hit(configuration.Name, c.GetString(matchedEndpointKey), mapping.(string))
+		}
+	}
+}
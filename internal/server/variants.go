@@ -0,0 +1,75 @@
+package server
+
+import (
+	"hash/fnv"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/variants"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	variantsKey   = "doppelganger.variants"
+	variantHeader = "X-Doppelganger-Variant"
+)
+
+// Variants hands the pinned variants to the mappings.
+func Variants(store *variants.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(variantsKey, store)
+		c.Next()
+	}
+}
+
+// withVariant returns the mapping answering with the variant of the
+// client: the one it was pinned to, or else one picked by hashing its key
+// so it gets the same on every request. The mapping's variantKey names
+// the client, or the server's clientKey when it has none, or else its IP.
+func withVariant(c *gin.Context, mapping config.Mapping) config.Mapping {
+	if len(mapping.Variants) == 0 {
+		return mapping
+	}
+	_, keyed := c.Get(clientKey)
+	client := c.GetString(clientKey)
+	if mapping.VariantKey != nil {
+		client, keyed = mapping.VariantKey.Evaluate(buildFetchers(c, nil)).(string), true
+	}
+	if !keyed {
+		client = c.ClientIP()
+	}
+
+	variant := pickVariant(mapping.Variants, client)
+	if store, ok := c.Get(variantsKey); ok {
+		if name, pinned := store.(*variants.Store).Pinned(client); pinned {
+			for _, candidate := range mapping.Variants {
+				if candidate.Name == name {
+					variant = candidate
+				}
+			}
+		}
+	}
+
+	if c.GetBool(debugHeadersKey) {
+		c.Header(variantHeader, variant.Name)
+	}
+	mapping.RespCode, mapping.Reason, mapping.Content = variant.RespCode, variant.Reason, variant.Content
+	return mapping
+}
+
+// pickVariant spreads clients over the variants by weight.
+func pickVariant(variants []config.Variant, client string) config.Variant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(client))
+	point := int(hash.Sum32() % uint32(total))
+	for _, variant := range variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chunkedWriter strips any Content-Length set by the handler so the
+// response is sent with chunked transfer encoding.
+type chunkedWriter struct {
+	gin.ResponseWriter
+}
+
+func (w chunkedWriter) WriteHeader(code int) {
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w chunkedWriter) WriteHeaderNow() {
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w chunkedWriter) Write(data []byte) (int, error) {
+	w.Header().Del("Content-Length")
+	return w.ResponseWriter.Write(data)
+}
+
+func (w chunkedWriter) WriteString(s string) (int, error) {
+	w.Header().Del("Content-Length")
+	return w.ResponseWriter.WriteString(s)
+}
+
+// framingWriter replaces the framing the handler chose: a forced
+// Content-Length, or a Transfer-Encoding of chunked or identity, which Go
+// sends as a body delimited by closing the connection.
+type framingWriter struct {
+	gin.ResponseWriter
+	contentLength    *int64
+	transferEncoding string
+}
+
+func (w framingWriter) frame() {
+	if w.contentLength != nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(*w.contentLength, 10))
+		return
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Transfer-Encoding", w.transferEncoding)
+}
+
+func (w framingWriter) WriteHeader(code int) {
+	w.frame()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w framingWriter) WriteHeaderNow() {
+	w.frame()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w framingWriter) Write(data []byte) (int, error) {
+	w.frame()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w framingWriter) WriteString(s string) (int, error) {
+	w.frame()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// statusWriter answers with code whenever the wrapped handler writes a
+// plain 200, leaving partial content and not modified answers untouched.
+type statusWriter struct {
+	gin.ResponseWriter
+	code int
+}
+
+func (w statusWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		code = w.code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+var errAborted = errors.New("response aborted on purpose")
+
+// abortWriter stops accepting body bytes once remaining reaches zero.
+type abortWriter struct {
+	http.ResponseWriter
+	remaining int64
+	aborted   bool
+}
+
+func (w *abortWriter) Write(data []byte) (int, error) {
+	if int64(len(data)) <= w.remaining {
+		w.remaining -= int64(len(data))
+		return w.ResponseWriter.Write(data)
+	}
+
+	n, err := w.ResponseWriter.Write(data[:w.remaining])
+	w.remaining = 0
+	w.aborted = true
+	if err != nil {
+		return n, err
+	}
+	return n, errAborted
+}
+
+// throttledWriter paces the body at bandwidth bytes per second, flushing
+// every tenth of a second worth of bytes so clients see the progress.
+type throttledWriter struct {
+	gin.ResponseWriter
+	c         *gin.Context
+	bandwidth int
+	start     time.Time
+	sent      int64
+}
+
+func (w *throttledWriter) Write(data []byte) (int, error) {
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+
+	chunk := max(w.bandwidth/10, 1)
+	written := 0
+	for written < len(data) {
+		end := min(written+chunk, len(data))
+		n, err := w.ResponseWriter.Write(data[written:end])
+		written += n
+		w.sent += int64(n)
+		if err != nil {
+			return written, err
+		}
+		w.ResponseWriter.Flush()
+
+		due := w.start.Add(time.Duration(w.sent) * time.Second / time.Duration(w.bandwidth))
+		delay(w.c, time.Until(due))
+	}
+	return written, nil
+}
+
+func (w *throttledWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,45 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+)
+
+var algorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// Check tells whether an algorithm and encoding are supported, so configs
+// fail when loaded rather than on the first request.
+func Check(algorithm string, encoding string) error {
+	if _, ok := algorithms[strings.ToLower(algorithm)]; !ok {
+		return errors.New("unknown HMAC algorithm " + algorithm + ", use sha1, sha256 or sha512")
+	}
+	if encoding != "" && encoding != "hex" && encoding != "base64" {
+		return errors.New("unknown signature encoding " + encoding + ", use hex or base64")
+	}
+	return nil
+}
+
+// HMAC signs data with the secret, encoded as hex unless told base64.
+func HMAC(algorithm string, secret string, data []byte, encoding string) (string, error) {
+	if err := Check(algorithm, encoding); err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(algorithms[strings.ToLower(algorithm)], []byte(secret))
+	mac.Write(data)
+	if encoding == "base64" {
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+	}
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,159 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// SigV4 is the content of an AWS Signature Version 4 Authorization header.
+type SigV4 struct {
+	AccessKey     string
+	Date          string
+	Region        string
+	Service       string
+	SignedHeaders []string
+	Signature     string
+}
+
+// ParseSigV4 reads a header like
+// "AWS4-HMAC-SHA256 Credential=AKID/20150830/us-east-1/iam/aws4_request,
+// SignedHeaders=host;x-amz-date, Signature=5d67...".
+func ParseSigV4(authorization string) (SigV4, bool) {
+	algorithm, rest, found := strings.Cut(authorization, " ")
+	if !found || algorithm != sigV4Algorithm {
+		return SigV4{}, false
+	}
+
+	var parsed SigV4
+	for _, part := range strings.Split(rest, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "Credential":
+			scope := strings.Split(value, "/")
+			if len(scope) != 5 || scope[4] != "aws4_request" {
+				return SigV4{}, false
+			}
+			parsed.AccessKey, parsed.Date, parsed.Region, parsed.Service = scope[0], scope[1], scope[2], scope[3]
+		case "SignedHeaders":
+			parsed.SignedHeaders = strings.Split(value, ";")
+		case "Signature":
+			parsed.Signature = value
+		}
+	}
+	if parsed.AccessKey == "" || len(parsed.SignedHeaders) == 0 || parsed.Signature == "" {
+		return SigV4{}, false
+	}
+	return parsed, true
+}
+
+// SigV4Request is what a SigV4 signature covers.
+type SigV4Request struct {
+	Method  string
+	URL     *url.URL
+	Host    string
+	Headers func(string) []string
+	Body    string
+}
+
+// Verify recomputes the signature of request with secret. Paths are
+// encoded once, like S3 does, which is the same for most paths.
+func (s SigV4) Verify(secret string, request SigV4Request) bool {
+	amzDate := first(request.Headers("X-Amz-Date"))
+	if amzDate == "" {
+		amzDate = first(request.Headers("Date"))
+	}
+	scope := strings.Join([]string{s.Date, s.Region, s.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex(canonicalRequest(s.SignedHeaders, request))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secret), s.Date)
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return hmac.Equal([]byte(expected), []byte(s.Signature))
+}
+
+func canonicalRequest(signedHeaders []string, request SigV4Request) string {
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		values := request.Headers(name)
+		if strings.EqualFold(name, "host") {
+			values = []string{request.Host}
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers.WriteString(strings.ToLower(name) + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+
+	payload := first(request.Headers("X-Amz-Content-Sha256"))
+	if payload == "" {
+		payload = hashHex(request.Body)
+	}
+
+	return strings.Join([]string{
+		request.Method,
+		path,
+		canonicalQuery(request.URL.Query()),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payload,
+	}, "\n")
+}
+
+func canonicalQuery(query url.Values) string {
+	type pair struct{ key, value string }
+	var pairs []pair
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, pair{uriEncode(key), uriEncode(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p.key + "=" + p.value
+	}
+	return strings.Join(encoded, "&")
+}
+
+// uriEncode escapes everything but the RFC 3986 unreserved characters.
+func uriEncode(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func hashHex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,69 @@
+package smtp
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message is a mail accepted by an SMTP server.
+type Message struct {
+	ID      string              `json:"id"`
+	Time    time.Time           `json:"time"`
+	Server  string              `json:"server"`
+	From    string              `json:"from"`
+	To      []string            `json:"to"`
+	Subject string              `json:"subject"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+	Raw     string              `json:"raw"`
+}
+
+// Mailbox keeps the messages received by every SMTP server.
+type Mailbox struct {
+	mutex    sync.Mutex
+	messages []Message
+	next     int
+}
+
+func NewMailbox() *Mailbox {
+	return &Mailbox{}
+}
+
+func (m *Mailbox) add(message Message) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.next++
+	message.ID = strconv.Itoa(m.next)
+	m.messages = append(m.messages, message)
+}
+
+// Messages returns the stored messages, oldest first.
+func (m *Mailbox) Messages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]Message{}, m.messages...)
+}
+
+// Clear drops every stored message.
+func (m *Mailbox) Clear() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = nil
+}
+
+func parseMessage(raw []byte) (string, map[string][]string, string) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", nil, string(raw)
+	}
+
+	body, _ := io.ReadAll(parsed.Body)
+	return parsed.Header.Get("Subject"), parsed.Header, string(body)
+}
@@ -0,0 +1,177 @@
+// Package smtp is a sink SMTP server accepting any mail, so tests can check
+// what would have been sent.
+package smtp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+)
+
+// maxMessageSize bounds a single message, like a real server would.
+const maxMessageSize = 10 << 20
+
+type Options struct {
+	Verbose bool
+	// Sockets are those passed by systemd, the port is bound when nil.
+	Sockets *systemd.Sockets
+	Mailbox *Mailbox
+}
+
+// Listen binds the server's port and serves it in the background until
+// the returned listener is closed.
+func Listen(configuration *config.Configuration, options Options) (io.Closer, error) {
+	listener, err := options.Sockets.Listen(configuration.Name, configuration.Port)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Serving SMTP %s on %s\n", configuration.Name, listener.Addr())
+	go Serve(listener, configuration, options)
+	return listener, nil
+}
+
+// Serve accepts connections until the listener is closed.
+func Serve(listener net.Listener, configuration *config.Configuration, options Options) {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			log.Println(err)
+			return
+		}
+		go handle(conn, configuration, options)
+	}
+}
+
+type session struct {
+	from string
+	to   []string
+}
+
+func handle(conn net.Conn, configuration *config.Configuration, options Options) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reply := func(code int, message string) error {
+		return text.PrintfLine("%d %s", code, message)
+	}
+
+	if reply(220, "doppelganger ESMTP ready") != nil {
+		return
+	}
+
+	var current session
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			err = reply(250, "doppelganger")
+		case "EHLO":
+			err = text.PrintfLine("250-doppelganger\r\n250-8BITMIME\r\n250 SIZE %d", maxMessageSize)
+		case "MAIL":
+			current = session{from: address(argument)}
+			err = reply(250, "OK")
+		case "RCPT":
+			current.to = append(current.to, address(argument))
+			err = reply(250, "OK")
+		case "DATA":
+			if len(current.to) == 0 {
+				err = reply(503, "RCPT first")
+				break
+			}
+			if err = reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+			raw, readErr := readData(text.R)
+			if readErr != nil {
+				err = reply(552, readErr.Error())
+				break
+			}
+			store(configuration, options, current, raw)
+			current = session{}
+			err = reply(250, "OK: queued")
+		case "RSET":
+			current = session{}
+			err = reply(250, "OK")
+		case "NOOP":
+			err = reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			err = reply(502, "Command not implemented")
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readData(reader *bufio.Reader) ([]byte, error) {
+	dot := textproto.NewReader(reader).DotReader()
+	var buffer bytes.Buffer
+	n, err := buffer.ReadFrom(io.LimitReader(dot, maxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxMessageSize {
+		io.Copy(io.Discard, dot)
+		return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+	}
+	return buffer.Bytes(), nil
+}
+
+func store(configuration *config.Configuration, options Options, current session, raw []byte) {
+	subject, headers, body := parseMessage(raw)
+	message := Message{
+		Time:    time.Now(),
+		Server:  configuration.Name,
+		From:    current.from,
+		To:      current.to,
+		Subject: subject,
+		Headers: headers,
+		Body:    body,
+		Raw:     string(raw),
+	}
+
+	if options.Verbose {
+		log.Printf("SMTP %s received mail from %s to %s: %s\n", configuration.Name, message.From, strings.Join(message.To, ", "), message.Subject)
+	}
+	if options.Mailbox != nil {
+		options.Mailbox.add(message)
+	}
+}
+
+// address extracts the mailbox of "FROM:<a@b.c> SIZE=12" style arguments.
+func address(argument string) string {
+	_, value, found := strings.Cut(argument, ":")
+	if !found {
+		return strings.TrimSpace(argument)
+	}
+	value = strings.TrimSpace(value)
+	if start := strings.Index(value, "<"); start >= 0 {
+		if end := strings.Index(value[start:], ">"); end >= 0 {
+			return value[start+1 : start+end]
+		}
+	}
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
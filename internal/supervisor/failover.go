@@ -0,0 +1,89 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrNoStandby is returned when failing over a server no standby was
+// added for.
+var ErrNoStandby = errors.New("no standby server")
+
+// Standby makes a server the standby of a primary one: it is left stopped
+// by StartAll and only starts once the primary fails over.
+func (s *Supervisor) Standby(name string, primary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := s.find(name)
+	if server == nil || s.find(primary) == nil {
+		return fmt.Errorf("server %s is standby for %s: %w", name, primary, ErrUnknown)
+	}
+	server.standby = primary
+	return nil
+}
+
+// Failover stops a primary server, so it refuses connections, and starts
+// its standby servers in its place.
+func (s *Supervisor) Failover(primary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	main, standbys, err := s.pair(primary)
+	if err != nil {
+		return err
+	}
+	main.endOutage()
+	if err := main.down(); err != nil {
+		log.Println(err)
+	}
+	for _, standby := range standbys {
+		standby.endOutage()
+		if err := standby.up(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Failed over server %s\n", primary)
+	return nil
+}
+
+// Failback stops the standby servers of a primary and starts it again.
+func (s *Supervisor) Failback(primary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	main, standbys, err := s.pair(primary)
+	if err != nil {
+		return err
+	}
+	for _, standby := range standbys {
+		standby.endOutage()
+		if err := standby.down(); err != nil {
+			log.Println(err)
+		}
+	}
+	main.endOutage()
+	if err := main.up(); err != nil {
+		return err
+	}
+	log.Printf("Failed back to server %s\n", primary)
+	return nil
+}
+
+func (s *Supervisor) pair(primary string) (*server, []*server, error) {
+	main := s.find(primary)
+	if main == nil {
+		return nil, nil, ErrUnknown
+	}
+	var standbys []*server
+	for _, candidate := range s.servers {
+		if candidate.standby == primary {
+			standbys = append(standbys, candidate)
+		}
+	}
+	if len(standbys) == 0 {
+		return nil, nil, fmt.Errorf("%w for %s", ErrNoStandby, primary)
+	}
+	return main, standbys, nil
+}
@@ -0,0 +1,109 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// OutageMode is how a server fails while unreachable.
+type OutageMode string
+
+const (
+	// OutageReset accepts new connections and resets them right away.
+	OutageReset OutageMode = "reset"
+	// OutageRefuse stops listening, so connections are refused.
+	OutageRefuse OutageMode = "refuse"
+)
+
+// ErrNoConnections is returned when resetting the connections of a UDP
+// server, which has none.
+var ErrNoConnections = errors.New("UDP servers have no connections to reset, use refuse")
+
+type outage struct {
+	mode     OutageMode
+	until    time.Time
+	timer    *time.Timer
+	resetter io.Closer
+}
+
+// Outage makes a server unreachable for a while, then brings it back as if
+// started again. A new outage replaces the current one.
+func (s *Supervisor) Outage(name string, mode OutageMode, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := s.find(name)
+	if server == nil {
+		return ErrUnknown
+	}
+	if mode != OutageReset && mode != OutageRefuse {
+		return fmt.Errorf("unknown outage mode %q", mode)
+	}
+	if mode == OutageReset && server.network == "udp" {
+		return ErrNoConnections
+	}
+
+	server.endOutage()
+	if err := server.down(); err != nil {
+		log.Println(err)
+	}
+	current := &outage{mode: mode, until: time.Now().Add(duration)}
+	if mode == OutageReset {
+		listener, err := s.sockets.Listen(server.name, server.port)
+		if err != nil {
+			return err
+		}
+		current.resetter = resetting(listener)
+	}
+	current.timer = time.AfterFunc(duration, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// A later outage or a manual start may have ended this one already.
+		if server.outage != current {
+			return
+		}
+		server.endOutage()
+		if err := server.up(); err != nil {
+			log.Printf("Server %s did not recover: %s\n", server.name, err)
+			return
+		}
+		log.Printf("Server %s recovered\n", server.name)
+	})
+	server.outage = current
+	log.Printf("Outage of server %s (%s) for %s\n", server.name, mode, duration)
+	return nil
+}
+
+// endOutage cancels the current outage, leaving the server stopped.
+func (s *server) endOutage() {
+	if s.outage == nil {
+		return
+	}
+	s.outage.timer.Stop()
+	if s.outage.resetter != nil {
+		s.outage.resetter.Close()
+	}
+	s.outage = nil
+}
+
+// resetting resets every connection made to the port of a stopped server.
+func resetting(listener net.Listener) io.Closer {
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+		}
+	}()
+	return listener
+}
@@ -0,0 +1,200 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+)
+
+// ErrUnknown is returned for names no server was added under.
+var ErrUnknown = errors.New("unknown server")
+
+// StartFunc binds a server and serves it in the background until the
+// returned closer is called.
+type StartFunc func() (io.Closer, error)
+
+// Status tells whether a server is currently serving, and the outage it
+// is going through, if any.
+type Status struct {
+	Name    string     `json:"name"`
+	Running bool       `json:"running"`
+	Outage  OutageMode `json:"outage,omitempty"`
+	Until   *time.Time `json:"until,omitempty"`
+	// StandbyFor names the primary server a standby takes over from.
+	StandbyFor string `json:"standbyFor,omitempty"`
+}
+
+type server struct {
+	name    string
+	network string
+	port    int
+	start   StartFunc
+	running io.Closer
+	outage  *outage
+	standby string
+}
+
+// Supervisor stops and starts servers independently of one another, e.g.
+// to simulate an upstream going down and coming back.
+type Supervisor struct {
+	mu      sync.Mutex
+	servers []*server
+	sockets *systemd.Sockets
+}
+
+// New returns a supervisor taking the ports of servers in outage from
+// sockets, or binding them when nil.
+func New(sockets *systemd.Sockets) *Supervisor {
+	return &Supervisor{sockets: sockets}
+}
+
+// Add registers a server listening on a "tcp" or "udp" port without
+// starting it.
+func (s *Supervisor) Add(name string, network string, port int, start StartFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.servers = append(s.servers, &server{name: name, network: network, port: port, start: start})
+}
+
+// StartAll starts every registered server but the standby ones at once,
+// returning when all of them listen. When any fails, the others are
+// stopped again and the failures returned.
+func (s *Supervisor) StartAll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := make([]error, len(s.servers))
+	var wg sync.WaitGroup
+	for i, server := range s.servers {
+		if server.standby != "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.up(); err != nil {
+				failures[i] = fmt.Errorf("server %s: %w", server.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := errors.Join(failures...)
+	if err != nil {
+		for _, server := range s.servers {
+			server.down()
+		}
+	}
+	return err
+}
+
+// Start starts a stopped server, ending its outage if any; starting a
+// running one does nothing.
+func (s *Supervisor) Start(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := s.find(name)
+	if server == nil {
+		return ErrUnknown
+	}
+	server.endOutage()
+	return server.up()
+}
+
+// Stop closes a server's listener and open connections; stopping a
+// stopped one does nothing.
+func (s *Supervisor) Stop(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := s.find(name)
+	if server == nil {
+		return ErrUnknown
+	}
+	server.endOutage()
+	return server.down()
+}
+
+// Restart stops a server, if running, and starts it again.
+func (s *Supervisor) Restart(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := s.find(name)
+	if server == nil {
+		return ErrUnknown
+	}
+	server.endOutage()
+	if err := server.down(); err != nil {
+		return err
+	}
+	return server.up()
+}
+
+// Listening counts the running servers.
+func (s *Supervisor) Listening() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, server := range s.servers {
+		if server.running != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Statuses returns the servers in the order they were added.
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, len(s.servers))
+	for i, server := range s.servers {
+		statuses[i] = Status{Name: server.name, Running: server.running != nil, StandbyFor: server.standby}
+		if server.outage != nil {
+			statuses[i].Outage = server.outage.mode
+			statuses[i].Until = &server.outage.until
+		}
+	}
+	return statuses
+}
+
+func (s *Supervisor) find(name string) *server {
+	for _, server := range s.servers {
+		if server.name == name {
+			return server
+		}
+	}
+	return nil
+}
+
+func (s *server) up() error {
+	if s.running != nil {
+		return nil
+	}
+	running, err := s.start()
+	if err != nil {
+		return err
+	}
+	s.running = running
+	return nil
+}
+
+func (s *server) down() error {
+	if s.running == nil {
+		return nil
+	}
+	err := s.running.Close()
+	s.running = nil
+	log.Printf("Stopped server %s\n", s.name)
+	return err
+}
@@ -0,0 +1,127 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first descriptor passed by socket activation.
+const listenFdsStart = 3
+
+// Sockets are the sockets systemd opened for the process, so it can start
+// on the first connection and restart without refusing any.
+type Sockets struct {
+	sockets []socket
+}
+
+type socket struct {
+	name     string
+	port     int
+	listener net.Listener
+	conn     net.PacketConn
+}
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Inherit takes the sockets passed through LISTEN_FDS, if any. The
+// variables are unset so commands run by EXEC content do not see them.
+func Inherit() (*Sockets, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	sockets := &Sockets{}
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return sockets, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return sockets, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := 0; i < count; i++ {
+		fd := listenFdsStart + i
+		// The descriptors are dup'ed by net, with close-on-exec set.
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		var inherited socket
+		if i < len(names) {
+			inherited.name = names[i]
+		}
+		if listener, err := net.FileListener(file); err == nil {
+			inherited.listener, inherited.port = listener, port(listener.Addr())
+		} else if conn, err := net.FilePacketConn(file); err == nil {
+			inherited.conn, inherited.port = conn, port(conn.LocalAddr())
+		} else {
+			file.Close()
+			return nil, fmt.Errorf("socket %d passed by systemd: %w", fd, err)
+		}
+		file.Close()
+		sockets.sockets = append(sockets.sockets, inherited)
+	}
+	return sockets, nil
+}
+
+// Listen returns the stream socket named after the server with
+// FileDescriptorName, or else bound to its port, binding the port itself
+// when systemd passed none. Inherited sockets are copied, so closing the
+// listener to stop a server leaves them open for the next start.
+func (s *Sockets) Listen(name string, port int) (net.Listener, error) {
+	if inherited := s.find(name, port, true); inherited != nil {
+		file, err := inherited.listener.(filer).File()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return net.FileListener(file)
+	}
+	return net.Listen("tcp", fmt.Sprintf(":%d", port))
+}
+
+// ListenPacket is Listen for datagram sockets.
+func (s *Sockets) ListenPacket(name string, port int) (net.PacketConn, error) {
+	if inherited := s.find(name, port, false); inherited != nil {
+		file, err := inherited.conn.(filer).File()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return net.FilePacketConn(file)
+	}
+	return net.ListenPacket("udp", fmt.Sprintf(":%d", port))
+}
+
+// find looks for a socket by name first, so names win over ports.
+func (s *Sockets) find(name string, port int, stream bool) *socket {
+	if s == nil {
+		return nil
+	}
+	for _, byName := range []bool{true, false} {
+		for i, inherited := range s.sockets {
+			if (inherited.listener != nil) != stream {
+				continue
+			}
+			if byName && inherited.name == name || !byName && inherited.port == port {
+				return &s.sockets[i]
+			}
+		}
+	}
+	return nil
+}
+
+func port(addr net.Addr) int {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.Port
+	case *net.UDPAddr:
+		return addr.Port
+	}
+	return 0
+}
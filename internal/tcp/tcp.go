@@ -0,0 +1,131 @@
+// Package tcp serves raw TCP mocks that reply with configured bytes when the
+// received data matches a rule.
+package tcp
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+)
+
+// maxBuffered bounds the unmatched bytes kept per connection.
+const maxBuffered = 64 << 10
+
+type Options struct {
+	Verbose bool
+	// Sockets are those passed by systemd, the port is bound when nil.
+	Sockets *systemd.Sockets
+}
+
+// Listen binds the server's port and serves it in the background until
+// the returned listener is closed.
+func Listen(configuration *config.Configuration, options Options) (io.Closer, error) {
+	listener, err := options.Sockets.Listen(configuration.Name, configuration.Port)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Serving TCP %s on %s\n", configuration.Name, listener.Addr())
+	go Serve(listener, configuration, options)
+	return listener, nil
+}
+
+// Serve accepts connections until the listener is closed.
+func Serve(listener net.Listener, configuration *config.Configuration, options Options) {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			log.Println(err)
+			return
+		}
+		go handle(conn, configuration, options)
+	}
+}
+
+func handle(conn net.Conn, configuration *config.Configuration, options Options) {
+	defer conn.Close()
+
+	if configuration.Greeting != nil {
+		if _, err := conn.Write(configuration.Greeting.Bytes); err != nil {
+			return
+		}
+	}
+
+	var buffered []byte
+	chunk := make([]byte, 4096)
+	for {
+		n, err := conn.Read(chunk)
+		if n > 0 {
+			buffered = append(buffered, chunk[:n]...)
+			if options.Verbose {
+				log.Printf("TCP %s received %s\n", configuration.Name, hex.EncodeToString(chunk[:n]))
+			}
+
+			var closing bool
+			buffered, closing = answer(conn, configuration, buffered)
+			if closing {
+				return
+			}
+			if len(buffered) > maxBuffered {
+				buffered = buffered[len(buffered)-maxBuffered:]
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// answer replies to every rule matching the buffered bytes, returning what
+// is left unconsumed and whether the connection was closed.
+func answer(conn net.Conn, configuration *config.Configuration, buffered []byte) ([]byte, bool) {
+	for len(buffered) > 0 {
+		rule, end := match(configuration.Rules, buffered)
+		if rule == nil {
+			return buffered, false
+		}
+		if end == 0 {
+			end = len(buffered)
+		}
+		buffered = buffered[end:]
+
+		time.Sleep(time.Duration(rule.Delay) * time.Millisecond)
+		if rule.Reply != nil {
+			if _, err := conn.Write(rule.Reply.Bytes); err != nil {
+				return nil, true
+			}
+		}
+
+		switch rule.Close {
+		case "graceful":
+			conn.Close()
+			return nil, true
+		case "reset":
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+			return nil, true
+		}
+	}
+	return buffered, false
+}
+
+func match(rules []config.TCPRule, buffered []byte) (*config.TCPRule, int) {
+	for i := range rules {
+		if rules[i].Match == nil {
+			return &rules[i], len(buffered)
+		}
+		if end := rules[i].Match.Find(buffered); end >= 0 {
+			return &rules[i], end
+		}
+	}
+	return nil, 0
+}
@@ -0,0 +1,110 @@
+package templating
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+var funcs = template.FuncMap{
+	"now":        time.Now,
+	"addDays":    addDays,
+	"addHours":   addHours,
+	"addMinutes": addMinutes,
+	"format":     format,
+	"parseDate":  ParseDate,
+	"add":        arithmetic(func(a, b float64) float64 { return a + b }),
+	"sub":        arithmetic(func(a, b float64) float64 { return a - b }),
+	"mul":        arithmetic(func(a, b float64) float64 { return a * b }),
+	"mod":        arithmetic(math.Mod),
+	"findBy":     findBy,
+}
+
+// layouts are the names accepted by format and ParseDate besides a raw Go layout.
+var layouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"date":        time.DateOnly,
+	"datetime":    time.DateTime,
+}
+
+func addDays(days int, t time.Time) time.Time {
+	return t.AddDate(0, 0, days)
+}
+
+func addHours(hours int, t time.Time) time.Time {
+	return t.Add(time.Duration(hours) * time.Hour)
+}
+
+func addMinutes(minutes int, t time.Time) time.Time {
+	return t.Add(time.Duration(minutes) * time.Minute)
+}
+
+// format renders t with a named layout, a Go layout, or "unix" for epoch seconds.
+func format(layout string, t time.Time) string {
+	if layout == "unix" {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	if named, ok := layouts[layout]; ok {
+		layout = named
+	}
+	return t.Format(layout)
+}
+
+// ParseDate reads a date such as a request value, using the same layouts as format.
+func ParseDate(layout string, value string) (time.Time, error) {
+	if layout == "unix" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0), nil
+	}
+	if named, ok := layouts[layout]; ok {
+		layout = named
+	}
+	return time.Parse(layout, value)
+}
+
+// arithmetic wraps a numeric operation so it accepts request strings as well
+// as numbers, e.g. {{ sub .Query.page 1 | mul 20 }}.
+func arithmetic(operation func(a, b float64) float64) func(a, b any) (string, error) {
+	return func(a, b any) (string, error) {
+		left, err := number(a)
+		if err != nil {
+			return "", err
+		}
+		right, err := number(b)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(operation(left, right), 'f', -1, 64), nil
+	}
+}
+
+// findBy returns the first record whose field equals value, compared as
+// text so request strings match numbers, e.g.
+// {{ (findBy .Datasets.users "id" .Params.id).name }}.
+func findBy(records []map[string]any, field string, value any) map[string]any {
+	for _, record := range records {
+		if fmt.Sprint(record[field]) == fmt.Sprint(value) {
+			return record
+		}
+	}
+	return nil
+}
+
+func number(value any) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
@@ -0,0 +1,136 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Data is the request information available to response templates.
+type Data struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Body    map[string]any
+	// Captures holds the named groups of the REGEX expressions that matched.
+	Captures map[string]string
+	// Values holds the mapping's evaluated values expressions.
+	Values map[string]any
+	// Datasets holds the records of the server's datasets, by name.
+	Datasets map[string][]map[string]any
+	// KV holds the values of the admin key-value store.
+	KV map[string]any
+}
+
+// Template is a JSON value whose strings are Go templates.
+type Template struct {
+	root node
+}
+
+type node interface {
+	render(data Data) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+type text struct {
+	template *template.Template
+}
+
+type object struct {
+	fields map[string]node
+}
+
+type array struct {
+	items []node
+}
+
+// Compile parses every string of a decoded JSON value as a template.
+func Compile(value any) (*Template, error) {
+	root, err := compile(value, "$")
+	if err != nil {
+		return nil, err
+	}
+	return &Template{root: root}, nil
+}
+
+// Render executes the templates against data, returning a new JSON value.
+func (t *Template) Render(data Data) (any, error) {
+	return t.root.render(data)
+}
+
+func compile(value any, path string) (node, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return literal{value: v}, nil
+		}
+		parsed, err := template.New(path).Funcs(funcs).Option("missingkey=zero").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template at %s: %w", path, err)
+		}
+		return text{template: parsed}, nil
+	case map[string]any:
+		fields := make(map[string]node, len(v))
+		for key, item := range v {
+			compiled, err := compile(item, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = compiled
+		}
+		return object{fields: fields}, nil
+	case []any:
+		items := make([]node, len(v))
+		for i, item := range v {
+			compiled, err := compile(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = compiled
+		}
+		return array{items: items}, nil
+	}
+	return literal{value: value}, nil
+}
+
+func (n literal) render(data Data) (any, error) {
+	return n.value, nil
+}
+
+func (n text) render(data Data) (any, error) {
+	var buffer bytes.Buffer
+	if err := n.template.Execute(&buffer, data); err != nil {
+		return nil, err
+	}
+	return buffer.String(), nil
+}
+
+func (n object) render(data Data) (any, error) {
+	result := make(map[string]any, len(n.fields))
+	for key, field := range n.fields {
+		value, err := field.render(data)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (n array) render(data Data) (any, error) {
+	result := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.render(data)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
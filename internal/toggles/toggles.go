@@ -0,0 +1,78 @@
+package toggles
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Toggle names a disabled mapping, or every mapping of the endpoint when
+// Mapping is empty. An empty Server applies to every server.
+type Toggle struct {
+	Server   string `json:"server,omitempty"`
+	Endpoint string `json:"endpoint"`
+	Mapping  string `json:"mapping,omitempty"`
+}
+
+// Store keeps the mappings and endpoints disabled at runtime.
+type Store struct {
+	mu       sync.Mutex
+	disabled map[Toggle]bool
+}
+
+func NewStore() *Store {
+	return &Store{disabled: map[Toggle]bool{}}
+}
+
+func (s *Store) Set(toggle Toggle, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if enabled {
+		delete(s.disabled, toggle)
+	} else {
+		s.disabled[toggle] = true
+	}
+}
+
+// Enabled tells whether a mapping may answer, that is neither it nor its
+// endpoint were disabled for its server or every server.
+func (s *Store) Enabled(server string, endpoint string, mapping string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, toggle := range []Toggle{
+		{server, endpoint, mapping},
+		{"", endpoint, mapping},
+		{server, endpoint, ""},
+		{"", endpoint, ""},
+	} {
+		if s.disabled[toggle] {
+			return false
+		}
+	}
+	return true
+}
+
+// Disabled returns the disabled mappings and endpoints, sorted.
+func (s *Store) Disabled() []Toggle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	disabled := make([]Toggle, 0, len(s.disabled))
+	for toggle := range s.disabled {
+		disabled = append(disabled, toggle)
+	}
+	slices.SortFunc(disabled, func(a, b Toggle) int {
+		return cmp.Or(cmp.Compare(a.Server, b.Server), cmp.Compare(a.Endpoint, b.Endpoint), cmp.Compare(a.Mapping, b.Mapping))
+	})
+	return disabled
+}
+
+// Reset enables everything again.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disabled = map[Toggle]bool{}
+}
@@ -0,0 +1,64 @@
+// Package udp listens for datagrams, such as statsd or syslog traffic, and
+// records them in the journal.
+package udp
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+)
+
+// maxDatagram is the biggest payload a UDP datagram can carry.
+const maxDatagram = 65535
+
+type Options struct {
+	Verbose bool
+	Journal journal.Recorder
+	// Sockets are those passed by systemd, the port is bound when nil.
+	Sockets *systemd.Sockets
+}
+
+// Listen binds the server's port and records datagrams in the background
+// until the returned connection is closed.
+func Listen(configuration *config.Configuration, options Options) (io.Closer, error) {
+	conn, err := options.Sockets.ListenPacket(configuration.Name, configuration.Port)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Serving UDP %s on %s\n", configuration.Name, conn.LocalAddr())
+	go Serve(conn, configuration, options)
+	return conn, nil
+}
+
+// Serve records datagrams until the connection is closed.
+func Serve(conn net.PacketConn, configuration *config.Configuration, options Options) {
+	buffer := make([]byte, maxDatagram)
+	for {
+		n, remote, err := conn.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			log.Println(err)
+			return
+		}
+
+		if options.Verbose {
+			log.Printf("UDP %s received %d bytes from %s: %q\n", configuration.Name, n, remote, buffer[:n])
+		}
+		if options.Journal != nil {
+			options.Journal.Record(journal.Entry{
+				Time:     time.Now(),
+				Server:   configuration.Name,
+				Protocol: config.ServerTypeUDP,
+				Remote:   remote.String(),
+				Body:     string(buffer[:n]),
+			})
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package uploads
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrUnknown is returned for uploads that were never started or were
+// cleared.
+var ErrUnknown = errors.New("unknown upload")
+
+// Upload is a resumable upload session. Total is -1 until the client
+// tells the final size.
+type Upload struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"`
+	Created  time.Time `json:"created"`
+	Size     int64     `json:"size"`
+	Total    int64     `json:"total"`
+	Complete bool      `json:"complete"`
+	SHA256   string    `json:"sha256,omitempty"`
+	content  []byte
+}
+
+// Store keeps the upload sessions in memory.
+type Store struct {
+	mu      sync.Mutex
+	uploads map[string]*Upload
+	ids     io.Reader
+}
+
+func NewStore() *Store {
+	return &Store{uploads: map[string]*Upload{}, ids: crand.Reader}
+}
+
+// Seed makes session ids repeat from one run to the next, so runs
+// following them in Location headers can be reproduced.
+func (s *Store) Seed(seed uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	s.ids = rand.NewChaCha8(key)
+}
+
+// Start opens an upload session for path, of total bytes or -1 when
+// unknown.
+func (s *Store) Start(path string, total int64) Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := make([]byte, 16)
+	io.ReadFull(s.ids, id)
+	upload := &Upload{ID: hex.EncodeToString(id), Path: path, Created: time.Now(), Total: total}
+	s.uploads[upload.ID] = upload
+	return *upload
+}
+
+// Write stores a chunk starting at offset start. Chunks overlapping the
+// received bytes are accepted, chunks leaving a gap are ignored so the
+// client resumes from what was received. total is -1 when still unknown.
+func (s *Store) Write(id string, start int64, chunk []byte, total int64) (Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		return Upload{}, ErrUnknown
+	}
+	if total >= 0 {
+		if upload.Total >= 0 && upload.Total != total {
+			return *upload, errors.New("upload size changed")
+		}
+		upload.Total = total
+	}
+	if upload.Complete || start > upload.Size {
+		return *upload, nil
+	}
+
+	end := start + int64(len(chunk))
+	if upload.Total >= 0 && end > upload.Total {
+		return *upload, errors.New("chunk past the end of the upload")
+	}
+	if end > upload.Size {
+		upload.content = append(upload.content, chunk[upload.Size-start:]...)
+		upload.Size = end
+	}
+	if upload.Size == upload.Total {
+		upload.Complete = true
+		sum := sha256.Sum256(upload.content)
+		upload.SHA256 = hex.EncodeToString(sum[:])
+	}
+	return *upload, nil
+}
+
+func (s *Store) Get(id string) (Upload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		return Upload{}, false
+	}
+	return *upload, true
+}
+
+// Content returns the bytes received so far.
+func (s *Store) Content(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(upload.content), true
+}
+
+// Uploads returns every session, oldest first.
+func (s *Store) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploads := make([]Upload, 0, len(s.uploads))
+	for _, upload := range s.uploads {
+		uploads = append(uploads, *upload)
+	}
+	slices.SortFunc(uploads, func(a, b Upload) int { return a.Created.Compare(b.Created) })
+	return uploads
+}
+
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.uploads = map[string]*Upload{}
+}
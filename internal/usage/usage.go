@@ -0,0 +1,72 @@
+package usage
+
+import "sync"
+
+// Mapping is the number of requests a mapping answered.
+type Mapping struct {
+	Server   string `json:"server"`
+	Endpoint string `json:"endpoint"`
+	Mapping  string `json:"mapping"`
+	Hits     int    `json:"hits"`
+}
+
+type key struct {
+	server, endpoint, mapping string
+}
+
+// Tracker counts hits per mapping, including the mappings never hit.
+type Tracker struct {
+	mu    sync.Mutex
+	hits  map[key]int
+	order []key
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{hits: map[key]int{}}
+}
+
+// Register adds a mapping to the report before it is hit.
+func (t *Tracker) Register(server string, endpoint string, mapping string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{server, endpoint, mapping}
+	if _, ok := t.hits[k]; !ok {
+		t.hits[k] = 0
+		t.order = append(t.order, k)
+	}
+}
+
+func (t *Tracker) Hit(server string, endpoint string, mapping string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{server, endpoint, mapping}
+	if _, ok := t.hits[k]; !ok {
+		t.order = append(t.order, k)
+	}
+	t.hits[k]++
+}
+
+// Report returns the hits of every mapping in config order.
+func (t *Tracker) Report() []Mapping {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := make([]Mapping, len(t.order))
+	for i, k := range t.order {
+		report[i] = Mapping{Server: k.server, Endpoint: k.endpoint, Mapping: k.mapping, Hits: t.hits[k]}
+	}
+	return report
+}
+
+// Unused returns the mappings that never answered a request.
+func (t *Tracker) Unused() []Mapping {
+	unused := []Mapping{}
+	for _, mapping := range t.Report() {
+		if mapping.Hits == 0 {
+			unused = append(unused, mapping)
+		}
+	}
+	return unused
+}
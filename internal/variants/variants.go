@@ -0,0 +1,57 @@
+package variants
+
+import (
+	"maps"
+	"sync"
+)
+
+// Store keeps the variants clients were pinned to through the admin API,
+// overriding the one their key picks.
+type Store struct {
+	mu   sync.Mutex
+	pins map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{pins: map[string]string{}}
+}
+
+// Pin makes the client get the variant with that name from every mapping
+// having one.
+func (s *Store) Pin(client string, variant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pins[client] = variant
+}
+
+func (s *Store) Pinned(client string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	variant, ok := s.pins[client]
+	return variant, ok
+}
+
+// Pins returns the variant of every pinned client.
+func (s *Store) Pins() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.pins)
+}
+
+// Unpin lets the client's key pick its variant again.
+func (s *Store) Unpin(client string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pins, client)
+}
+
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.pins)
+}
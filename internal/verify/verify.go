@@ -0,0 +1,204 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/parsers"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+)
+
+// Matcher selects journaled requests and tells how many are expected.
+// Path may hold :name and *name segments, read by PATH expressions.
+type Matcher struct {
+	Server string                   `json:"server,omitempty"`
+	Method string                   `json:"method,omitempty"`
+	Path   string                   `json:"path,omitempty"`
+	Params []expressions.Expression `json:"params,omitempty"`
+	// Count expects exactly that many requests, otherwise AtLeast and
+	// AtMost bound them. With no bounds at least one is expected.
+	Count   *int `json:"count,omitempty"`
+	AtLeast *int `json:"atLeast,omitempty"`
+	AtMost  *int `json:"atMost,omitempty"`
+}
+
+func (matcher *Matcher) UnmarshalJSON(data []byte) error {
+	type Alias Matcher
+	aux := &struct {
+		Params []json.RawMessage `json:"params"`
+		*Alias
+	}{Alias: (*Alias)(matcher)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	matcher.Params = make([]expressions.Expression, len(aux.Params))
+	for i, raw := range aux.Params {
+		param, err := expressions.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("error building param %d: %w", i, err)
+		}
+		if param.ReturnType() != reflect.Bool {
+			return fmt.Errorf("param %d must be a boolean expression", i)
+		}
+		matcher.Params[i] = param
+	}
+	return nil
+}
+
+// Request is a verification: every matcher must find the expected number
+// of requests and, InOrder, their first requests must come in order.
+type Request struct {
+	Requests []Matcher `json:"requests"`
+	InOrder  bool      `json:"inOrder,omitempty"`
+}
+
+type Result struct {
+	Passed  bool     `json:"passed"`
+	Results []Report `json:"results"`
+	// Order explains why the requests were not received in order.
+	Order string `json:"order,omitempty"`
+}
+
+// Report is the outcome of a single matcher.
+type Report struct {
+	Matcher int    `json:"matcher"`
+	Count   int    `json:"count"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Verify checks the journal entries against the verification.
+func Verify(entries []journal.Entry, request Request) Result {
+	result := Result{Passed: true, Results: make([]Report, len(request.Requests))}
+	firsts := make([]int, len(request.Requests))
+
+	for i, matcher := range request.Requests {
+		matches := Find(entries, matcher)
+		firsts[i] = -1
+		if len(matches) > 0 {
+			firsts[i] = matches[0]
+		}
+
+		report := Report{Matcher: i, Count: len(matches)}
+		report.Message = matcher.check(len(matches))
+		report.Passed = report.Message == ""
+		result.Passed = result.Passed && report.Passed
+		result.Results[i] = report
+	}
+
+	if request.InOrder {
+		for i := 1; i < len(firsts); i++ {
+			if firsts[i-1] >= 0 && firsts[i] >= 0 && firsts[i] < firsts[i-1] {
+				result.Passed = false
+				result.Order = fmt.Sprintf("matcher %d was first received before matcher %d", i, i-1)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (matcher Matcher) check(count int) string {
+	switch {
+	case matcher.Count != nil && count != *matcher.Count:
+		return fmt.Sprintf("expected %d requests, received %d", *matcher.Count, count)
+	case matcher.AtLeast != nil && count < *matcher.AtLeast:
+		return fmt.Sprintf("expected at least %d requests, received %d", *matcher.AtLeast, count)
+	case matcher.AtMost != nil && count > *matcher.AtMost:
+		return fmt.Sprintf("expected at most %d requests, received %d", *matcher.AtMost, count)
+	case matcher.Count == nil && matcher.AtLeast == nil && matcher.AtMost == nil && count == 0:
+		return "expected at least 1 request, received none"
+	}
+	return ""
+}
+
+// Find returns the indexes of the HTTP entries selected by the matcher.
+func Find(entries []journal.Entry, matcher Matcher) []int {
+	matches := []int{}
+	for i, entry := range entries {
+		if entry.Protocol != "" ||
+			matcher.Server != "" && entry.Server != matcher.Server ||
+			matcher.Method != "" && !strings.EqualFold(entry.Method, matcher.Method) {
+			continue
+		}
+		params, ok := matchPath(matcher.Path, entry.Path)
+		if !ok {
+			continue
+		}
+		if allHold(matcher.Params, fetchers(entry, params)) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// matchPath compares a path to a pattern of :name (one segment) and *name
+// (the rest) segments, returning the values of the named segments.
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	params := map[string]string{}
+	if pattern == "" {
+		return params, true
+	}
+
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range patternSegments {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			params[segment[1:]] = "/" + strings.Join(pathSegments[min(i, len(pathSegments)):], "/")
+			return params, true
+		case i >= len(pathSegments):
+			return nil, false
+		case strings.HasPrefix(segment, ":"):
+			params[segment[1:]] = pathSegments[i]
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+	return params, len(patternSegments) == len(pathSegments)
+}
+
+func allHold(params []expressions.Expression, fetchers expressions.EvaluationFetchers) bool {
+	for _, param := range params {
+		if !param.Evaluate(fetchers).(bool) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchers lets expressions read a journaled request like a live one.
+func fetchers(entry journal.Entry, params map[string]string) expressions.EvaluationFetchers {
+	query, _ := url.ParseQuery(entry.Query)
+	headers := http.Header(entry.Headers)
+	contentType, _, _ := mime.ParseMediaType(headers.Get("Content-Type"))
+
+	var body map[string]any
+	if _, ok := parsers.Lookup(contentType); ok && entry.Body != "" {
+		body, _ = parsers.Parse([]byte(entry.Body), contentType)
+	}
+
+	return expressions.EvaluationFetchers{
+		BodyFetcher:        body,
+		QueryFetcher:       query.Get,
+		QueryArrayFetcher:  func(key string) []string { return query[key] },
+		ParamFetcher:       func(key string) string { return params[key] },
+		HeaderFetcher:      headers.Get,
+		HeaderArrayFetcher: headers.Values,
+		RemoteAddrFetcher:  func() string { return entry.Remote },
+		HostFetcher:        func() string { return headers.Get("Host") },
+		TLSFetcher:         func() bool { return false },
+		ContentTypeFetcher: func() string { return contentType },
+		RawBodyFetcher:     func() string { return entry.Body },
+		MethodFetcher:      func() string { return entry.Method },
+		URLFetcher:         func() *url.URL { return &url.URL{Path: entry.Path, RawQuery: entry.Query} },
+		Captures:           make(map[string]string),
+	}
+}
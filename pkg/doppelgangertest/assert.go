@@ -0,0 +1,91 @@
+package doppelgangertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dsa-ferreira/doppelganger/internal/verify"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+)
+
+// Matcher selects requests like the verifications of the admin API: by
+// Server, Method, Path, whose :name and *name segments are read by PATH
+// expressions, and boolean Params. It can be decoded from the same JSON,
+// leaving out the expected counts.
+type Matcher struct {
+	Server string                   `json:"server,omitempty"`
+	Method string                   `json:"method,omitempty"`
+	Path   string                   `json:"path,omitempty"`
+	Params []expressions.Expression `json:"params,omitempty"`
+}
+
+func (matcher *Matcher) UnmarshalJSON(data []byte) error {
+	var decoded verify.Matcher
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*matcher = Matcher{Server: decoded.Server, Method: decoded.Method, Path: decoded.Path, Params: decoded.Params}
+	return nil
+}
+
+func (matcher Matcher) verify() verify.Matcher {
+	return verify.Matcher{Server: matcher.Server, Method: matcher.Method, Path: matcher.Path, Params: matcher.Params}
+}
+
+// Received counts the requests the server got that the matcher selects.
+func (server *Server) Received(matcher Matcher) int {
+	return len(verify.Find(server.entries(), matcher.verify()))
+}
+
+// AssertReceived fails the test unless the server got exactly times
+// requests selected by the matcher, listing the requests it got instead.
+func AssertReceived(t testing.TB, server *Server, matcher Matcher, times int) bool {
+	t.Helper()
+	entries := server.entries()
+	expected := matcher.verify()
+	expected.Count = &times
+	result := verify.Verify(entries, verify.Request{Requests: []verify.Matcher{expected}})
+	if result.Passed {
+		return true
+	}
+
+	var received strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&received, "\n\t%s %s", entry.Method, entry.Path)
+		if entry.Query != "" {
+			received.WriteString("?" + entry.Query)
+		}
+	}
+	if received.Len() == 0 {
+		received.WriteString(" none")
+	}
+	t.Errorf("doppelgangertest: %s: %s, requests received:%s", describe(matcher), result.Results[0].Message, received.String())
+	return false
+}
+
+// AssertNotReceived fails the test if the server got any request selected
+// by the matcher.
+func AssertNotReceived(t testing.TB, server *Server, matcher Matcher) bool {
+	t.Helper()
+	return AssertReceived(t, server, matcher, 0)
+}
+
+func describe(matcher Matcher) string {
+	method, path := matcher.Method, matcher.Path
+	if method == "" {
+		method = "any method"
+	}
+	if path == "" {
+		path = "any path"
+	}
+	description := method + " " + path
+	if matcher.Server != "" {
+		description += " on " + matcher.Server
+	}
+	if len(matcher.Params) > 0 {
+		description += fmt.Sprintf(" with %d params", len(matcher.Params))
+	}
+	return description
+}
@@ -0,0 +1,140 @@
+// Package doppelgangertest serves doppelganger configurations from Go tests
+// through net/http/httptest, without binding real ports.
+//
+//	server := doppelgangertest.NewServer(t, "testdata/payments.json")
+//	resp, err := http.Get(server.URL + "/api/payments/1")
+//	doppelgangertest.AssertReceived(t, server, doppelgangertest.Matcher{Method: "GET", Path: "/api/payments/:id"}, 1)
+package doppelgangertest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/server"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// Option tweaks how a configuration is loaded.
+type Option func(*settings)
+
+type settings struct {
+	server   string
+	profiles []string
+}
+
+// WithServer picks a server of a multi server configuration by name. The
+// first server is used otherwise.
+func WithServer(name string) Option {
+	return func(s *settings) {
+		s.server = name
+	}
+}
+
+// WithProfiles activates profiles, like the -profile flag.
+func WithProfiles(profiles ...string) Option {
+	return func(s *settings) {
+		s.profiles = append(s.profiles, profiles...)
+	}
+}
+
+// NewHandler builds the handler of a server from a config file.
+func NewHandler(path string, options ...Option) (http.Handler, error) {
+	servers, err := config.ParseConfiguration(path)
+	if err != nil {
+		return nil, err
+	}
+	return newHandler(servers, options, nil)
+}
+
+// NewHandlerFromJSON builds the handler of a server from an inline config.
+// Relative paths are resolved from the working directory.
+func NewHandlerFromJSON(data []byte, options ...Option) (http.Handler, error) {
+	servers, err := config.ParseData(data, "inline.json")
+	if err != nil {
+		return nil, err
+	}
+	return newHandler(servers, options, nil)
+}
+
+// newHandler builds the handler of the first server, recording the
+// requests it gets when requests is not nil.
+func newHandler(servers *config.Servers, options []Option, requests *journal.MemoryRecorder) (http.Handler, error) {
+	var s settings
+	for _, option := range options {
+		option(&s)
+	}
+
+	servers.ApplyProfiles(s.profiles)
+	if s.server != "" {
+		if err := servers.Only([]string{s.server}); err != nil {
+			return nil, err
+		}
+	}
+	if len(servers.Configurations) == 0 {
+		return nil, errors.New("no server to serve")
+	}
+	if servers.Configurations[0].Type != "" && servers.Configurations[0].Type != config.ServerTypeHTTP {
+		return nil, errors.New("server " + servers.Configurations[0].Name + " is not an HTTP server")
+	}
+
+	serverOptions := server.Options{Quiet: true}
+	if requests != nil {
+		serverOptions.Journal = requests
+	}
+	return server.NewHandler(&servers.Configurations[0], serverOptions)
+}
+
+// Server is a running doppelganger stub, keeping the requests it got for
+// AssertReceived.
+type Server struct {
+	*httptest.Server
+	requests *journal.MemoryRecorder
+}
+
+// NewServer serves a config file until the test ends, failing the test if
+// the configuration is invalid.
+func NewServer(t testing.TB, path string, options ...Option) *Server {
+	t.Helper()
+	servers, err := config.ParseConfiguration(path)
+	if err != nil {
+		t.Fatalf("doppelgangertest: %s", err)
+	}
+	return start(t, servers, options)
+}
+
+// NewServerFromJSON serves an inline config until the test ends.
+func NewServerFromJSON(t testing.TB, data []byte, options ...Option) *Server {
+	t.Helper()
+	servers, err := config.ParseData(data, "inline.json")
+	if err != nil {
+		t.Fatalf("doppelgangertest: %s", err)
+	}
+	return start(t, servers, options)
+}
+
+func start(t testing.TB, servers *config.Servers, options []Option) *Server {
+	t.Helper()
+	server := &Server{requests: journal.NewMemoryRecorder(journal.Limits{})}
+	handler, err := newHandler(servers, options, server.requests)
+	if err != nil {
+		t.Fatalf("doppelgangertest: %s", err)
+	}
+	server.Server = httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// entries returns every request received so far. Requests are recorded
+// once their response has been produced; those still being answered are
+// left out rather than waited for.
+func (server *Server) entries() []journal.Entry {
+	return server.requests.Entries()
+}
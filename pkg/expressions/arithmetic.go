@@ -0,0 +1,117 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+type ArithmeticExpression struct {
+	name      string
+	left      Expression
+	right     Expression
+	operation func(left, right float64) float64
+}
+
+func (e ArithmeticExpression) Evaluate(fetchers EvaluationFetchers) any {
+	left := toNumber(e.left.Evaluate(fetchers))
+	right := toNumber(e.right.Evaluate(fetchers))
+	return e.operation(left, right)
+}
+
+func (e ArithmeticExpression) ReturnType() reflect.Kind {
+	return reflect.Float64
+}
+
+func arithmeticFactory(name string, operation func(left, right float64) float64) ExpressionFactory {
+	return func(body map[string]json.RawMessage) (Expression, error) {
+		left, err := BuildExpression(body["left"])
+		if err != nil {
+			return nil, err
+		}
+		right, err := BuildExpression(body["right"])
+		if err != nil {
+			return nil, err
+		}
+
+		if !isNumeric(left) || !isNumeric(right) {
+			return nil, errors.New("invalid blocks: " + name + " left and right must be numbers or strings")
+		}
+
+		return ArithmeticExpression{name: name, left: left, right: right, operation: operation}, nil
+	}
+}
+
+var (
+	addFactory      = arithmeticFactory("ADD", func(left, right float64) float64 { return left + right })
+	subtractFactory = arithmeticFactory("SUBTRACT", func(left, right float64) float64 { return left - right })
+	multiplyFactory = arithmeticFactory("MULTIPLY", func(left, right float64) float64 { return left * right })
+	moduloFactory   = arithmeticFactory("MODULO", math.Mod)
+)
+
+type NumberValueExpression struct {
+	value float64
+}
+
+func (e NumberValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return e.value
+}
+
+func (e NumberValueExpression) ReturnType() reflect.Kind {
+	return reflect.Float64
+}
+
+func numberValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	var value float64
+	if err := json.Unmarshal(body["value"], &value); err != nil {
+		return nil, errors.New("invalid block: NUMBER value must be a number, got " + describeJson(body["value"]))
+	}
+	return NumberValueExpression{value: value}, nil
+}
+
+type ToNumberExpression struct {
+	value Expression
+}
+
+func (e ToNumberExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return toNumber(e.value.Evaluate(fetchers))
+}
+
+func (e ToNumberExpression) ReturnType() reflect.Kind {
+	return reflect.Float64
+}
+
+func toNumberFactory(body map[string]json.RawMessage) (Expression, error) {
+	value, err := BuildExpression(body["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNumeric(value) {
+		return nil, errors.New("invalid blocks: TO_NUMBER value must be a string")
+	}
+
+	return ToNumberExpression{value: value}, nil
+}
+
+func isNumeric(expression Expression) bool {
+	kind := expression.ReturnType()
+	return kind == reflect.Float64 || kind == reflect.String
+}
+
+// toNumber reads request values as numbers; anything unparseable counts as zero.
+func toNumber(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0
+		}
+		return number
+	}
+	return 0
+}
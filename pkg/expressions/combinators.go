@@ -0,0 +1,108 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+type XorExpression struct {
+	expressions []Expression
+}
+
+func (e XorExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return countTrue(e.expressions, fetchers) == 1
+}
+
+func (e XorExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func xorFactory(body map[string]json.RawMessage) (Expression, error) {
+	expressions, err := buildBoolExpressions("XOR", body["expressions"])
+	if err != nil {
+		return nil, err
+	}
+	return XorExpression{expressions: expressions}, nil
+}
+
+// NOfExpression counts how many expressions hold; unset bounds are not checked.
+type NOfExpression struct {
+	expressions []Expression
+	atLeast     *int
+	exactly     *int
+	atMost      *int
+}
+
+func (e NOfExpression) Evaluate(fetchers EvaluationFetchers) any {
+	count := countTrue(e.expressions, fetchers)
+	if e.atLeast != nil && count < *e.atLeast {
+		return false
+	}
+	if e.exactly != nil && count != *e.exactly {
+		return false
+	}
+	if e.atMost != nil && count > *e.atMost {
+		return false
+	}
+	return true
+}
+
+func (e NOfExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func nOfFactory(body map[string]json.RawMessage) (Expression, error) {
+	expressions, err := buildBoolExpressions("N_OF", body["expressions"])
+	if err != nil {
+		return nil, err
+	}
+
+	expression := NOfExpression{expressions: expressions}
+	for key, bound := range map[string]**int{"atLeast": &expression.atLeast, "exactly": &expression.exactly, "atMost": &expression.atMost} {
+		if body[key] == nil {
+			continue
+		}
+		var value int
+		if err := json.Unmarshal(body[key], &value); err != nil {
+			return nil, errors.New("invalid block: N_OF " + key + " must be an integer")
+		}
+		*bound = &value
+	}
+
+	if expression.atLeast == nil && expression.exactly == nil && expression.atMost == nil {
+		return nil, errors.New("invalid block: N_OF must have atLeast, exactly or atMost")
+	}
+
+	return expression, nil
+}
+
+func countTrue(expressions []Expression, fetchers EvaluationFetchers) int {
+	count := 0
+	for _, expression := range expressions {
+		if expression.Evaluate(fetchers).(bool) {
+			count++
+		}
+	}
+	return count
+}
+
+func buildBoolExpressions(name string, rawExpressions json.RawMessage) ([]Expression, error) {
+	rawMessages, err := parseJsonList(rawExpressions, name+" expressions")
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := make([]Expression, len(rawMessages))
+	for i, item := range rawMessages {
+		expression, err := BuildExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		if expression.ReturnType() != reflect.Bool {
+			return nil, errors.New("invalid block: " + name + " values must be bool")
+		}
+		expressions[i] = expression
+	}
+	return expressions, nil
+}
@@ -0,0 +1,106 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/templating"
+)
+
+// DateExpression compares dates parsed with layout; a missing bound means now.
+// Values that fail to parse never match.
+type DateExpression struct {
+	name    string
+	value   Expression
+	from    Expression
+	to      Expression
+	layout  string
+	compare func(value, from, to time.Time) bool
+}
+
+func (e DateExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value, ok := e.parse(e.value, fetchers)
+	if !ok {
+		return false
+	}
+	from, ok := e.parse(e.from, fetchers)
+	if !ok {
+		return false
+	}
+	to, ok := e.parse(e.to, fetchers)
+	if !ok {
+		return false
+	}
+	return e.compare(value, from, to)
+}
+
+func (e DateExpression) parse(expression Expression, fetchers EvaluationFetchers) (time.Time, bool) {
+	if expression == nil {
+		return time.Now(), true
+	}
+	date, err := templating.ParseDate(e.layout, expression.Evaluate(fetchers).(string))
+	return date, err == nil
+}
+
+func (e DateExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func dateBeforeFactory(body map[string]json.RawMessage) (Expression, error) {
+	return buildDateExpression("DATE_BEFORE", body, "reference", "", func(value, reference, _ time.Time) bool {
+		return value.Before(reference)
+	})
+}
+
+func dateAfterFactory(body map[string]json.RawMessage) (Expression, error) {
+	return buildDateExpression("DATE_AFTER", body, "reference", "", func(value, reference, _ time.Time) bool {
+		return value.After(reference)
+	})
+}
+
+func dateBetweenFactory(body map[string]json.RawMessage) (Expression, error) {
+	return buildDateExpression("DATE_BETWEEN", body, "from", "to", func(value, from, to time.Time) bool {
+		return !value.Before(from) && !value.After(to)
+	})
+}
+
+func buildDateExpression(name string, body map[string]json.RawMessage, fromKey string, toKey string, compare func(value, from, to time.Time) bool) (Expression, error) {
+	expression := DateExpression{name: name, layout: time.RFC3339, compare: compare}
+	var err error
+	if body["layout"] != nil {
+		if expression.layout, err = parseJsonString(body["layout"]); err != nil {
+			return nil, err
+		}
+	}
+	if expression.value, err = buildDateOperand(name, body["value"]); err != nil {
+		return nil, err
+	}
+	if expression.value == nil {
+		return nil, errors.New("invalid block: " + name + " must have value attribute")
+	}
+	if expression.from, err = buildDateOperand(name, body[fromKey]); err != nil {
+		return nil, err
+	}
+	if toKey != "" {
+		if expression.to, err = buildDateOperand(name, body[toKey]); err != nil {
+			return nil, err
+		}
+	}
+	return expression, nil
+}
+
+func buildDateOperand(name string, data json.RawMessage) (Expression, error) {
+	if data == nil {
+		return nil, nil
+	}
+	operand, err := BuildExpression(data)
+	if err != nil {
+		return nil, err
+	}
+	if operand.ReturnType() != reflect.String {
+		return nil, errors.New("invalid blocks: " + name + " dates must be strings")
+	}
+	return operand, nil
+}
@@ -0,0 +1,25 @@
+// Package expressions builds and evaluates the expression trees mappings
+// match requests with, so tools and custom builds can construct, check and
+// evaluate them like a config file does.
+//
+// A tree is written as nested JSON blocks naming their "type":
+//
+//	param, err := expressions.Parse([]byte(`{
+//		"type": "EQUALS",
+//		"left": { "type": "QUERY", "id": "page" },
+//		"right": { "type": "STRING", "value": "1" }
+//	}`))
+//	matched := param.Evaluate(fetchers).(bool)
+//
+// Every expression evaluates to the Go type matching its ReturnType:
+// string for reflect.String, []string for reflect.Slice, bool for
+// reflect.Bool and float64 for reflect.Float64. Mapping params must be
+// reflect.Bool. Factories check the kinds of the expressions they nest
+// when building, so a tree that builds never fails a type assertion.
+//
+// Expressions reading the request implement Optional to tell whether it
+// sent the value at all.
+//
+// Custom types are added with Register before any config is parsed, and
+// their expressions must marshal back to the block they were built from.
+package expressions
@@ -0,0 +1,70 @@
+package expressions
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Observed is the value a request gave to a value expression.
+type Observed struct {
+	Expression json.RawMessage `json:"expression"`
+	Value      any             `json:"value"`
+}
+
+// Explain evaluates the value expressions nested in a boolean expression,
+// like the BODY side of an EQUALS, to tell what the request gave them.
+// Literals are left out since they do not depend on the request, and values
+// the request did not send are observed as nil.
+func Explain(expression Expression, fetchers EvaluationFetchers) []Observed {
+	data, err := json.Marshal(expression)
+	if err != nil {
+		return nil
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil
+	}
+
+	var observed []Observed
+	explain(tree, fetchers, &observed)
+	return observed
+}
+
+func explain(node any, fetchers EvaluationFetchers, observed *[]Observed) {
+	switch node := node.(type) {
+	case []any:
+		for _, item := range node {
+			explain(item, fetchers, observed)
+		}
+	case map[string]any:
+		typ, ok := node["type"].(string)
+		if !ok || typ == "STRING" || typ == "NUMBER" {
+			return
+		}
+		data, _ := json.Marshal(node)
+		nested, err := Parse(data)
+		if err != nil {
+			return
+		}
+		if nested.ReturnType() != reflect.Bool {
+			data, _ = json.Marshal(nested)
+			var value any
+			if !missing(nested, fetchers) {
+				value = nested.Evaluate(fetchers)
+			}
+			*observed = append(*observed, Observed{Expression: data, Value: value})
+			return
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			if key != "type" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			explain(node[key], fetchers, observed)
+		}
+	}
+}
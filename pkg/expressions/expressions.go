@@ -0,0 +1,941 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ExpressionFactory builds an expression from the attributes of its JSON
+// block, "type" included, returning an error for invalid attributes.
+type ExpressionFactory func(map[string]json.RawMessage) (Expression, error)
+
+// EvaluationFetchers give expressions access to the request they evaluate.
+type EvaluationFetchers struct {
+	BodyFetcher        map[string]any
+	QueryFetcher       func(string) string
+	QueryArrayFetcher  func(string) []string
+	ParamFetcher       func(string) string
+	HeaderFetcher      func(string) string
+	HeaderArrayFetcher func(string) []string
+	RemoteAddrFetcher  func() string
+	HostFetcher        func() string
+	TLSFetcher         func() bool
+	ContentTypeFetcher func() string
+	RawBodyFetcher     func() string
+	MethodFetcher      func() string
+	URLFetcher         func() *url.URL
+	// KVFetcher reads the values set through the admin API, nil when there
+	// are none to read.
+	KVFetcher func(string) (any, bool)
+	// Captures collects the named groups of every REGEX that matched so far.
+	Captures map[string]string
+}
+
+// Expression is a node of an expression tree.
+type Expression interface {
+	// Evaluate returns a value of the Go type matching ReturnType, see the
+	// package documentation.
+	Evaluate(fetchers EvaluationFetchers) any
+	ReturnType() reflect.Kind
+	// MarshalJSON writes the expression back in the format BuildExpression reads.
+	json.Marshaler
+}
+
+func init() {
+	registry = map[string]ExpressionFactory{
+		"AND":          andFactory,
+		"OR":           orFactory,
+		"NOT":          notFactory,
+		"BODY":         bodyValueFactory,
+		"BODY_ARRAY":   bodyArrayValueFactory("BODY_ARRAY"),
+		"FORM_ARRAY":   bodyArrayValueFactory("FORM_ARRAY"),
+		"QUERY":        queryValueFactory,
+		"QUERY_ARRAY":  queryArrayValueFactory,
+		"PATH":         pathValueFactory,
+		"HEADER":       headerValueFactory,
+		"HEADER_ARRAY": headerArrayValueFactory,
+		"COOKIE":       cookieValueFactory,
+		"REMOTE_IP":    remoteIPValueFactory,
+		"REMOTE_PORT":  remotePortValueFactory,
+		"HOST":         hostValueFactory,
+		"TLS":          tlsValueFactory,
+		"CONTENT_TYPE": contentTypeValueFactory,
+		"STRING":       stringValueFactory,
+		"EQUALS":       equalsFactory,
+		"REGEX":        regexFactory,
+		"CONTAINS":     containsFactory,
+		"NOT_EQUALS":   notEqualsFactory,
+		"NOT_CONTAINS": notContainsFactory,
+		"IS_EMPTY":     isEmptyFactory,
+		"EXISTS":       existsFactory,
+		"DEFAULT":      defaultFactory,
+		"COALESCE":     coalesceFactory,
+		"CAPTURE":      captureValueFactory,
+		"KV":           kvValueFactory,
+		"NUMBER":       numberValueFactory,
+		"TO_NUMBER":    toNumberFactory,
+		"ADD":          addFactory,
+		"SUBTRACT":     subtractFactory,
+		"MULTIPLY":     multiplyFactory,
+		"MODULO":       moduloFactory,
+		"DATE_BEFORE":  dateBeforeFactory,
+		"DATE_AFTER":   dateAfterFactory,
+		"DATE_BETWEEN": dateBetweenFactory,
+		"IF":           ifFactory,
+		"XOR":          xorFactory,
+		"N_OF":         nOfFactory,
+		"RAW_BODY":     rawBodyValueFactory,
+		"HMAC":         hmacFactory,
+		"SIGV4":        sigV4Factory,
+		"SIGV4_VALID":  sigV4ValidFactory,
+	}
+}
+
+type AndExpression struct {
+	expressions []Expression
+}
+
+func (e AndExpression) Evaluate(fetchers EvaluationFetchers) any {
+	for _, expression := range e.expressions {
+		if !expression.Evaluate(fetchers).(bool) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e AndExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func andFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	rawExpressions := body["expressions"]
+	rawMessages, err := parseJsonList(rawExpressions, "AND expressions")
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := make([]Expression, len(rawMessages))
+
+	for i, item := range rawMessages {
+		expression, err := BuildExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		if expression.ReturnType() != reflect.Bool {
+			return nil, errors.New("invalid block: AND values must be bool")
+		}
+		expressions[i] = expression
+	}
+
+	return AndExpression{expressions: expressions}, nil
+}
+
+type OrExpression struct {
+	expressions []Expression
+}
+
+func (e OrExpression) Evaluate(fetchers EvaluationFetchers) any {
+	for _, expression := range e.expressions {
+		if expression.Evaluate(fetchers).(bool) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e OrExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func orFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	rawExpressions := body["expressions"]
+	rawMessages, err := parseJsonList(rawExpressions, "OR expressions")
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := make([]Expression, len(rawMessages))
+
+	for i, item := range rawMessages {
+		expression, err := BuildExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		if expression.ReturnType() != reflect.Bool {
+			return nil, errors.New("invalid block: OR values must be bool")
+		}
+		expressions[i] = expression
+	}
+
+	return OrExpression{expressions: expressions}, nil
+}
+
+type NotExpression struct {
+	expression Expression
+}
+
+func (e NotExpression) Evaluate(fetchers EvaluationFetchers) any {
+	result := e.expression.Evaluate(fetchers).(bool)
+	return !result
+}
+
+func (e NotExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func notFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	expression, err := BuildExpression(body["expression"])
+	if err != nil {
+		return nil, err
+	}
+
+	if expression.ReturnType() != reflect.Bool {
+		return nil, errors.New("invalid block: NOT value must be bool")
+	}
+
+	return NotExpression{expression: expression}, nil
+}
+
+type ContainsExpression struct {
+	list   Expression
+	values []Expression
+}
+
+func (e ContainsExpression) Evaluate(fetchers EvaluationFetchers) any {
+	listValues := e.list.Evaluate(fetchers).([]string)
+
+	for _, value := range e.values {
+		if missing(value, fetchers) || !slices.Contains(listValues, value.Evaluate(fetchers).(string)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e ContainsExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func containsFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	rawExpressions := body["values"]
+	if rawExpressions == nil {
+		return nil, errors.New("invalid block: CONTAINS must have values attribute")
+	}
+	rawMessages, err := parseJsonList(rawExpressions, "CONTAINS values")
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := make([]Expression, len(rawMessages))
+
+	for i, item := range rawMessages {
+		expression, err := BuildExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		if expression.ReturnType() != reflect.String {
+			return nil, errors.New("invalid block. CONTAINS values must be string")
+		}
+		expressions[i] = expression
+	}
+
+	rawList := body["list"]
+	if rawList == nil {
+		return nil, errors.New("invalid block: CONTAINS must have list attribute")
+	}
+	list, err := BuildExpression(rawList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if list.ReturnType() != reflect.Slice {
+		return nil, errors.New("invalid block: CONTAINS list must be slice")
+	}
+
+	return ContainsExpression{list: list, values: expressions}, nil
+}
+
+type NotContainsExpression struct {
+	contains ContainsExpression
+}
+
+// Evaluate is false when the list or a value is missing, like CONTAINS.
+func (e NotContainsExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if missing(e.contains.list, fetchers) {
+		return false
+	}
+	listValues := e.contains.list.Evaluate(fetchers).([]string)
+
+	for _, value := range e.contains.values {
+		if missing(value, fetchers) || slices.Contains(listValues, value.Evaluate(fetchers).(string)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e NotContainsExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func notContainsFactory(body map[string]json.RawMessage) (Expression, error) {
+	contains, err := containsFactory(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return NotContainsExpression{contains: contains.(ContainsExpression)}, nil
+}
+
+type IfExpression struct {
+	condition Expression
+	then      Expression
+	otherwise Expression
+}
+
+func (e IfExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if e.condition.Evaluate(fetchers).(bool) {
+		return e.then.Evaluate(fetchers)
+	}
+	return e.otherwise.Evaluate(fetchers)
+}
+
+func (e IfExpression) ReturnType() reflect.Kind {
+	return e.then.ReturnType()
+}
+
+func ifFactory(body map[string]json.RawMessage) (Expression, error) {
+	condition, err := BuildExpression(body["condition"])
+	if err != nil {
+		return nil, err
+	}
+	then, err := BuildExpression(body["then"])
+	if err != nil {
+		return nil, err
+	}
+	otherwise, err := BuildExpression(body["else"])
+	if err != nil {
+		return nil, err
+	}
+
+	if condition.ReturnType() != reflect.Bool {
+		return nil, errors.New("invalid blocks: IF condition must be a bool")
+	}
+	if then.ReturnType() != otherwise.ReturnType() {
+		return nil, errors.New("invalid blocks: IF then and else must be the same kind")
+	}
+
+	return IfExpression{condition: condition, then: then, otherwise: otherwise}, nil
+}
+
+type EqualsExpression struct {
+	right Expression
+	left  Expression
+}
+
+func (e EqualsExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if missing(e.right, fetchers) || missing(e.left, fetchers) {
+		return false
+	}
+	switch e.right.ReturnType() {
+	case reflect.String:
+		{
+			right := e.right.Evaluate(fetchers).(string)
+			left := e.left.Evaluate(fetchers).(string)
+			return right == left
+		}
+	case reflect.Slice:
+		{
+			right := e.right.Evaluate(fetchers).([]string)
+			left := e.left.Evaluate(fetchers).([]string)
+			return reflect.DeepEqual(right, left)
+		}
+	case reflect.Bool:
+		{
+			right := e.right.Evaluate(fetchers).(bool)
+			left := e.left.Evaluate(fetchers).(bool)
+			return right == left
+		}
+	case reflect.Float64:
+		{
+			right := e.right.Evaluate(fetchers).(float64)
+			left := e.left.Evaluate(fetchers).(float64)
+			return right == left
+		}
+	default:
+		// Unreachable, equalsFactory only builds the kinds above.
+		return false
+	}
+}
+
+func (e EqualsExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func equalsFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	right, err := BuildExpression(body["right"])
+	if err != nil {
+		return nil, err
+	}
+	left, err := BuildExpression(body["left"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if right.ReturnType() != left.ReturnType() {
+		return nil, errors.New("invalid blocks: EQUALS right and left must be the same kind")
+	}
+	switch right.ReturnType() {
+	case reflect.String, reflect.Slice, reflect.Bool, reflect.Float64:
+	default:
+		return nil, fmt.Errorf("invalid blocks: EQUALS cannot compare %s values", right.ReturnType())
+	}
+
+	return EqualsExpression{left: left, right: right}, nil
+}
+
+type NotEqualsExpression struct {
+	equals EqualsExpression
+}
+
+// Evaluate is false when an operand is missing, like EQUALS.
+func (e NotEqualsExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if missing(e.equals.right, fetchers) || missing(e.equals.left, fetchers) {
+		return false
+	}
+	return !e.equals.Evaluate(fetchers).(bool)
+}
+
+func (e NotEqualsExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func notEqualsFactory(body map[string]json.RawMessage) (Expression, error) {
+	equals, err := equalsFactory(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return NotEqualsExpression{equals: equals.(EqualsExpression)}, nil
+}
+
+type IsEmptyExpression struct {
+	value Expression
+}
+
+func (e IsEmptyExpression) Evaluate(fetchers EvaluationFetchers) any {
+	switch e.value.ReturnType() {
+	case reflect.String:
+		return e.value.Evaluate(fetchers).(string) == ""
+	default:
+		// isEmptyFactory only builds strings and slices.
+		return len(e.value.Evaluate(fetchers).([]string)) == 0
+	}
+}
+
+func (e IsEmptyExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func isEmptyFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	value, err := BuildExpression(body["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	if value.ReturnType() != reflect.String && value.ReturnType() != reflect.Slice {
+		return nil, errors.New("invalid block: IS_EMPTY value must be string or slice")
+	}
+
+	return IsEmptyExpression{value: value}, nil
+}
+
+type RegexExpression struct {
+	value   Expression
+	pattern *regexp.Regexp
+}
+
+func (e RegexExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if missing(e.value, fetchers) {
+		return false
+	}
+	value := e.value.Evaluate(fetchers).(string)
+	match := e.pattern.FindStringSubmatch(value)
+	if match == nil {
+		return false
+	}
+
+	if fetchers.Captures != nil {
+		for i, name := range e.pattern.SubexpNames() {
+			if name != "" {
+				fetchers.Captures[name] = match[i]
+			}
+		}
+	}
+	return true
+}
+
+func (e RegexExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func regexFactory(body map[string]json.RawMessage) (Expression, error) {
+
+	value, err := BuildExpression(body["value"])
+	if err != nil {
+		return nil, err
+	}
+	rawPattern, err := parseJsonString(body["pattern"])
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := regexp.Compile(rawPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.ReturnType() != reflect.String {
+		return nil, errors.New("invalid blocks: REGEX value is not string")
+	}
+
+	return RegexExpression{value: value, pattern: pattern}, nil
+}
+
+type BodyValueExpression struct {
+	id string
+}
+
+// Evaluate returns the body attribute as text, or an empty string when it
+// is missing or null.
+func (e BodyValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value := fetchers.BodyFetcher[e.id]
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (e BodyValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func bodyValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return BodyValueExpression{id: id}, nil
+}
+
+// BodyArrayValueExpression reads a body attribute as a list, like a
+// repeated form field or a JSON array. Single values make a list of one.
+type BodyArrayValueExpression struct {
+	typ string
+	id  string
+}
+
+func (e BodyArrayValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	switch value := fetchers.BodyFetcher[e.id].(type) {
+	case nil:
+		return []string{}
+	case []string:
+		return value
+	case []any:
+		values := make([]string, len(value))
+		for i, item := range value {
+			values[i] = fmt.Sprintf("%v", item)
+		}
+		return values
+	default:
+		return []string{fmt.Sprintf("%v", value)}
+	}
+}
+
+func (e BodyArrayValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(make([]string, 0)).Kind()
+}
+
+// bodyArrayValueFactory builds both BODY_ARRAY and its FORM_ARRAY alias,
+// keeping the name used for exports.
+func bodyArrayValueFactory(typ string) ExpressionFactory {
+	return func(body map[string]json.RawMessage) (Expression, error) {
+		id, err := parseJsonString(body["id"])
+		if err != nil {
+			return nil, err
+		}
+		return BodyArrayValueExpression{typ: typ, id: id}, nil
+	}
+}
+
+type QueryValueExpression struct {
+	id string
+}
+
+func (e QueryValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value := fetchers.QueryFetcher(e.id)
+	return value
+
+}
+
+func (e QueryValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func queryValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return QueryValueExpression{id: id}, nil
+}
+
+type QueryArrayValueExpression struct {
+	id string
+}
+
+func (e QueryArrayValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value := fetchers.QueryFetcher(e.id)
+	if strings.Contains(value, ",") {
+		return strings.Split(value, ",")
+	}
+	return fetchers.QueryArrayFetcher(e.id)
+}
+
+func (e QueryArrayValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(make([]string, 0)).Kind()
+}
+
+func queryArrayValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return QueryArrayValueExpression{id: id}, nil
+}
+
+type PathValueExpression struct {
+	id string
+}
+
+func (e PathValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value := fetchers.ParamFetcher(e.id)
+	return value
+
+}
+
+func (e PathValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func pathValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return PathValueExpression{id: id}, nil
+}
+
+type CaptureValueExpression struct {
+	id string
+}
+
+func (e CaptureValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return fetchers.Captures[e.id]
+}
+
+func (e CaptureValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func captureValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return CaptureValueExpression{id: id}, nil
+}
+
+// KVValueExpression is a value of the admin key-value store, as text for
+// anything but strings, empty when it is not set.
+type KVValueExpression struct {
+	id string
+}
+
+func (e KVValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if fetchers.KVFetcher == nil {
+		return ""
+	}
+	value, ok := fetchers.KVFetcher(e.id)
+	if !ok {
+		return ""
+	}
+	if text, isText := value.(string); isText {
+		return text
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func (e KVValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func kvValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return KVValueExpression{id: id}, nil
+}
+
+type HeaderValueExpression struct {
+	id string
+}
+
+func (e HeaderValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return fetchers.HeaderFetcher(e.id)
+}
+
+func (e HeaderValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func headerValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return HeaderValueExpression{id: id}, nil
+}
+
+type HeaderArrayValueExpression struct {
+	id string
+}
+
+func (e HeaderArrayValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	values := make([]string, 0)
+	for _, header := range fetchers.HeaderArrayFetcher(e.id) {
+		for _, value := range strings.Split(header, ",") {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+func (e HeaderArrayValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(make([]string, 0)).Kind()
+}
+
+func headerArrayValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return HeaderArrayValueExpression{id: id}, nil
+}
+
+type CookieValueExpression struct {
+	id string
+}
+
+// Evaluate returns the value of the first cookie named id, or an empty
+// string.
+func (e CookieValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	value, _ := e.lookup(fetchers)
+	return value
+}
+
+func (e CookieValueExpression) lookup(fetchers EvaluationFetchers) (string, bool) {
+	for _, line := range fetchers.HeaderArrayFetcher("Cookie") {
+		cookies, err := http.ParseCookie(line)
+		if err != nil {
+			continue
+		}
+		for _, cookie := range cookies {
+			if cookie.Name == e.id {
+				return cookie.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e CookieValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func cookieValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	id, err := parseJsonString(body["id"])
+	if err != nil {
+		return nil, err
+	}
+	return CookieValueExpression{id: id}, nil
+}
+
+type RemoteIPValueExpression struct{}
+
+func (e RemoteIPValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	host, _, err := net.SplitHostPort(fetchers.RemoteAddrFetcher())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func (e RemoteIPValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func remoteIPValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return RemoteIPValueExpression{}, nil
+}
+
+type RemotePortValueExpression struct{}
+
+func (e RemotePortValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	_, port, err := net.SplitHostPort(fetchers.RemoteAddrFetcher())
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+func (e RemotePortValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func remotePortValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return RemotePortValueExpression{}, nil
+}
+
+type HostValueExpression struct{}
+
+func (e HostValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return fetchers.HostFetcher()
+}
+
+func (e HostValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func hostValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return HostValueExpression{}, nil
+}
+
+type TLSValueExpression struct{}
+
+func (e TLSValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return fetchers.TLSFetcher()
+}
+
+func (e TLSValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func tlsValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return TLSValueExpression{}, nil
+}
+
+type ContentTypeValueExpression struct{}
+
+func (e ContentTypeValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return strings.ToLower(fetchers.ContentTypeFetcher())
+}
+
+func (e ContentTypeValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func contentTypeValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return ContentTypeValueExpression{}, nil
+}
+
+type StringValueExpression struct {
+	value string
+}
+
+func (e StringValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return e.value
+}
+
+func (e StringValueExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf("").Kind()
+}
+
+func stringValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	value, err := parseJsonString(body["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	return StringValueExpression{value: value}, nil
+}
+
+// BuildExpression builds the expression tree of a JSON block.
+func BuildExpression(data []byte) (Expression, error) {
+	var body map[string]json.RawMessage
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, &body); errors.As(err, &typeErr) {
+		return nil, errors.New("expression must be an object, not " + typeErr.Value)
+	} else if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, errors.New("expression must be an object, not null")
+	}
+
+	var typ string
+	if err := json.Unmarshal(body["type"], &typ); err != nil {
+		return nil, errors.New("expression type must be a string")
+	}
+	factory, ok := Lookup(typ)
+	if !ok {
+		return nil, errors.New("unknown expression type " + typ)
+	}
+	return factory(body)
+}
+
+func parseJsonString(data []byte) (string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", errors.New("invalid block: expected a string attribute, got " + describeJson(data))
+	}
+	return s, nil
+}
+
+// parseJsonList reads the expressions of a list attribute, named by what in
+// the error returned when it is not a list.
+func parseJsonList(data []byte, what string) ([]json.RawMessage, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.New("invalid block: " + what + " must be a list, got " + describeJson(data))
+	}
+	return list, nil
+}
+
+func describeJson(data []byte) string {
+	if len(data) == 0 {
+		return "nothing"
+	}
+	if len(data) > 32 {
+		return string(data[:32]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,59 @@
+package expressions_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/pkg/expressions"
+)
+
+var seeds = []string{
+	`{"type":"EQUALS","left":{"type":"QUERY","id":"page"},"right":{"type":"STRING","value":"1"}}`,
+	`{"type":"AND","expressions":[{"type":"TLS"},{"type":"NOT","expression":{"type":"IS_EMPTY","value":{"type":"BODY_ARRAY","id":"tags"}}}]}`,
+	`{"type":"N_OF","atLeast":1,"expressions":[{"type":"REGEX","value":{"type":"HEADER","id":"X"},"pattern":"^(?P<id>\\d+)$"}]}`,
+	`{"type":"DATE_BETWEEN","value":{"type":"QUERY","id":"at"},"from":{"type":"STRING","value":"2024-01-01T00:00:00Z"}}`,
+	`{"type":"COALESCE","values":[{"type":"HEADER","id":"X-Id"},{"type":"DEFAULT","value":{"type":"QUERY","id":"id"},"default":{"type":"STRING","value":""}}]}`,
+	`{"type":"ADD","left":{"type":"NUMBER","value":1},"right":{"type":"TO_NUMBER","value":{"type":"BODY","id":"n"}}}`,
+	`{"type":"HMAC","secret":"s","value":{"type":"RAW_BODY"}}`,
+	`{"type":"IF","condition":{"type":"EXISTS","value":{"type":"COOKIE","id":"c"}},"then":{"type":"KV","id":"a"},"else":{"type":"SIGV4","component":"region"}}`,
+	`{"type":"CONTAINS","list":{"type":"QUERY_ARRAY","id":"a"},"values":[{"type":"CAPTURE","id":"id"}]}`,
+	`[]`, `null`, `{"type":1}`, `{"type":"AND","expressions":{}}`, `{"type":"NUMBER","value":"x"}`,
+}
+
+// FuzzParse builds arbitrary blocks without recovering panics: factories
+// must return errors, and what builds must marshal back to a block that
+// builds the same way.
+func FuzzParse(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expression, err := expressions.BuildExpression(data)
+		if err != nil {
+			return
+		}
+		marshalled, err := json.Marshal(expression)
+		if err != nil {
+			t.Fatalf("marshalling %s: %s", data, err)
+		}
+		rebuilt, err := expressions.BuildExpression(marshalled)
+		if err != nil {
+			t.Fatalf("rebuilding %s from %s: %s", marshalled, data, err)
+		}
+		if rebuilt.ReturnType() != expression.ReturnType() {
+			t.Fatalf("%s rebuilt as %s, built as %s", marshalled, rebuilt.ReturnType(), expression.ReturnType())
+		}
+	})
+}
+
+// FuzzParseData parses arbitrary configurations holding expressions, which
+// must be rejected with an error rather than a panic.
+func FuzzParseData(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add([]byte(`{"port":1,"endpoint":[{"path":"/","verb":"GET","mappings":[{"params":[` + seed + `]}]}]}`))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config.ParseData(data, "fuzz.json")
+	})
+}
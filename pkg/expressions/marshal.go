@@ -0,0 +1,223 @@
+package expressions
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+type field struct {
+	key   string
+	value any
+}
+
+// marshalExpression writes the "type" key first and then the attributes in
+// the order they are documented, so exported configs read like hand written
+// ones. Nil attributes are left out.
+func marshalExpression(typ string, fields ...field) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{"type":`)
+	encoded, _ := json.Marshal(typ)
+	buffer.Write(encoded)
+
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(f.key)
+		buffer.WriteByte(',')
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func (e AndExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("AND", field{"expressions", e.expressions})
+}
+
+func (e OrExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("OR", field{"expressions", e.expressions})
+}
+
+func (e NotExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("NOT", field{"expression", e.expression})
+}
+
+func (e XorExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("XOR", field{"expressions", e.expressions})
+}
+
+func (e NOfExpression) MarshalJSON() ([]byte, error) {
+	fields := []field{{"expressions", e.expressions}}
+	if e.atLeast != nil {
+		fields = append(fields, field{"atLeast", *e.atLeast})
+	}
+	if e.exactly != nil {
+		fields = append(fields, field{"exactly", *e.exactly})
+	}
+	if e.atMost != nil {
+		fields = append(fields, field{"atMost", *e.atMost})
+	}
+	return marshalExpression("N_OF", fields...)
+}
+
+func (e ContainsExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("CONTAINS", field{"list", e.list}, field{"values", e.values})
+}
+
+func (e NotContainsExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("NOT_CONTAINS", field{"list", e.contains.list}, field{"values", e.contains.values})
+}
+
+func (e IfExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("IF", field{"condition", e.condition}, field{"then", e.then}, field{"else", e.otherwise})
+}
+
+func (e EqualsExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("EQUALS", field{"left", e.left}, field{"right", e.right})
+}
+
+func (e NotEqualsExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("NOT_EQUALS", field{"left", e.equals.left}, field{"right", e.equals.right})
+}
+
+func (e IsEmptyExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("IS_EMPTY", field{"value", e.value})
+}
+
+func (e RegexExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("REGEX", field{"value", e.value}, field{"pattern", e.pattern.String()})
+}
+
+func (e ArithmeticExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression(e.name, field{"left", e.left}, field{"right", e.right})
+}
+
+func (e NumberValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("NUMBER", field{"value", e.value})
+}
+
+func (e ToNumberExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("TO_NUMBER", field{"value", e.value})
+}
+
+func (e DateExpression) MarshalJSON() ([]byte, error) {
+	var layout any
+	if e.layout != time.RFC3339 {
+		layout = e.layout
+	}
+	if e.name == "DATE_BETWEEN" {
+		return marshalExpression(e.name, field{"value", e.value}, field{"from", e.from}, field{"to", e.to}, field{"layout", layout})
+	}
+	return marshalExpression(e.name, field{"value", e.value}, field{"reference", e.from}, field{"layout", layout})
+}
+
+func (e ExistsExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("EXISTS", field{"value", e.value})
+}
+
+func (e DefaultExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("DEFAULT", field{"value", e.value}, field{"default", e.fallback})
+}
+
+func (e CoalesceExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("COALESCE", field{"values", e.values})
+}
+
+func (e BodyValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("BODY", field{"id", e.id})
+}
+
+func (e QueryValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("QUERY", field{"id", e.id})
+}
+
+func (e BodyArrayValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression(e.typ, field{"id", e.id})
+}
+
+func (e QueryArrayValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("QUERY_ARRAY", field{"id", e.id})
+}
+
+func (e PathValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("PATH", field{"id", e.id})
+}
+
+func (e CaptureValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("CAPTURE", field{"id", e.id})
+}
+
+func (e KVValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("KV", field{"id", e.id})
+}
+
+func (e HeaderValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("HEADER", field{"id", e.id})
+}
+
+func (e HeaderArrayValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("HEADER_ARRAY", field{"id", e.id})
+}
+
+func (e CookieValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("COOKIE", field{"id", e.id})
+}
+
+func (e RemoteIPValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("REMOTE_IP")
+}
+
+func (e RemotePortValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("REMOTE_PORT")
+}
+
+func (e HostValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("HOST")
+}
+
+func (e TLSValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("TLS")
+}
+
+func (e ContentTypeValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("CONTENT_TYPE")
+}
+
+func (e StringValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("STRING", field{"value", e.value})
+}
+
+func (e RawBodyValueExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("RAW_BODY")
+}
+
+func (e HMACExpression) MarshalJSON() ([]byte, error) {
+	fields := []field{{"algorithm", e.algorithm}, {"secret", e.secret}}
+	if e.encoding != "" {
+		fields = append(fields, field{"encoding", e.encoding})
+	}
+	if e.prefix != "" {
+		fields = append(fields, field{"prefix", e.prefix})
+	}
+	if _, raw := e.value.(RawBodyValueExpression); !raw {
+		fields = append(fields, field{"value", e.value})
+	}
+	return marshalExpression("HMAC", fields...)
+}
+
+func (e SigV4Expression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("SIGV4", field{"component", e.component})
+}
+
+func (e SigV4ValidExpression) MarshalJSON() ([]byte, error) {
+	return marshalExpression("SIGV4_VALID", field{"secret", e.secret})
+}
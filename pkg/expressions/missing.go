@@ -0,0 +1,208 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// Optional is implemented by the expressions reading a part of the request
+// that may not have been sent, like a body attribute or a query param.
+// Those evaluate to an empty value when missing, which EQUALS, CONTAINS and
+// REGEX never match, nor do NOT_EQUALS and NOT_CONTAINS, so an absent value
+// cannot pass for an empty one or for a different one.
+type Optional interface {
+	Present(fetchers EvaluationFetchers) bool
+}
+
+// missing tells whether an expression reads a value the request did not
+// send.
+func missing(expression Expression, fetchers EvaluationFetchers) bool {
+	optional, ok := expression.(Optional)
+	return ok && !optional.Present(fetchers)
+}
+
+// Present is false for absent attributes and null JSON values.
+func (e BodyValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return fetchers.BodyFetcher[e.id] != nil
+}
+
+func (e BodyArrayValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return fetchers.BodyFetcher[e.id] != nil
+}
+
+// Present is true for a param given without a value, as in ?debug.
+func (e QueryValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return len(fetchers.QueryArrayFetcher(e.id)) > 0
+}
+
+func (e QueryArrayValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return len(fetchers.QueryArrayFetcher(e.id)) > 0
+}
+
+// Present is false for an empty path param, which only a wildcard matching
+// nothing or an id the route does not declare give.
+func (e PathValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return fetchers.ParamFetcher(e.id) != ""
+}
+
+func (e HeaderValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return len(fetchers.HeaderArrayFetcher(e.id)) > 0
+}
+
+func (e HeaderArrayValueExpression) Present(fetchers EvaluationFetchers) bool {
+	return len(fetchers.HeaderArrayFetcher(e.id)) > 0
+}
+
+func (e CookieValueExpression) Present(fetchers EvaluationFetchers) bool {
+	_, ok := e.lookup(fetchers)
+	return ok
+}
+
+func (e CaptureValueExpression) Present(fetchers EvaluationFetchers) bool {
+	_, ok := fetchers.Captures[e.id]
+	return ok
+}
+
+func (e KVValueExpression) Present(fetchers EvaluationFetchers) bool {
+	if fetchers.KVFetcher == nil {
+		return false
+	}
+	_, ok := fetchers.KVFetcher(e.id)
+	return ok
+}
+
+type ExistsExpression struct {
+	value Expression
+}
+
+func (e ExistsExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return !missing(e.value, fetchers)
+}
+
+func (e ExistsExpression) ReturnType() reflect.Kind {
+	return reflect.TypeOf(true).Kind()
+}
+
+func existsFactory(body map[string]json.RawMessage) (Expression, error) {
+	value, err := BuildExpression(body["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := value.(Optional); !ok {
+		return nil, errors.New("invalid block: EXISTS value must read the request, like BODY or QUERY")
+	}
+
+	return ExistsExpression{value: value}, nil
+}
+
+// DefaultExpression stands in a fallback for a value the request did not
+// send.
+type DefaultExpression struct {
+	value    Expression
+	fallback Expression
+}
+
+func (e DefaultExpression) Evaluate(fetchers EvaluationFetchers) any {
+	if missing(e.value, fetchers) {
+		return e.fallback.Evaluate(fetchers)
+	}
+	return e.value.Evaluate(fetchers)
+}
+
+func (e DefaultExpression) ReturnType() reflect.Kind {
+	return e.value.ReturnType()
+}
+
+// Present is false only when the fallback is missing as well.
+func (e DefaultExpression) Present(fetchers EvaluationFetchers) bool {
+	return !missing(e.value, fetchers) || !missing(e.fallback, fetchers)
+}
+
+func defaultFactory(body map[string]json.RawMessage) (Expression, error) {
+	value, err := BuildExpression(body["value"])
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := BuildExpression(body["default"])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := value.(Optional); !ok {
+		return nil, errors.New("invalid block: DEFAULT value must read the request, like BODY or QUERY")
+	}
+	if value.ReturnType() != fallback.ReturnType() {
+		return nil, errors.New("invalid blocks: DEFAULT value and default must be the same kind")
+	}
+
+	return DefaultExpression{value: value, fallback: fallback}, nil
+}
+
+// CoalesceExpression is the first of its values the request sent with
+// something in it, like an id accepted from a header or a query param.
+type CoalesceExpression struct {
+	values []Expression
+}
+
+// Evaluate returns the last value when none of them has something in it.
+func (e CoalesceExpression) Evaluate(fetchers EvaluationFetchers) any {
+	for _, expression := range e.values {
+		if missing(expression, fetchers) {
+			continue
+		}
+		if value := expression.Evaluate(fetchers); !empty(value) {
+			return value
+		}
+	}
+	return e.values[len(e.values)-1].Evaluate(fetchers)
+}
+
+func (e CoalesceExpression) ReturnType() reflect.Kind {
+	return e.values[0].ReturnType()
+}
+
+func (e CoalesceExpression) Present(fetchers EvaluationFetchers) bool {
+	for _, expression := range e.values {
+		if !missing(expression, fetchers) {
+			return true
+		}
+	}
+	return false
+}
+
+func coalesceFactory(body map[string]json.RawMessage) (Expression, error) {
+	rawMessages, err := parseJsonList(body["values"], "COALESCE values")
+	if err != nil {
+		return nil, err
+	}
+	if len(rawMessages) == 0 {
+		return nil, errors.New("invalid block: COALESCE must have values")
+	}
+
+	values := make([]Expression, len(rawMessages))
+	for i, item := range rawMessages {
+		expression, err := BuildExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && expression.ReturnType() != values[0].ReturnType() {
+			return nil, errors.New("invalid blocks: COALESCE values must be the same kind")
+		}
+		values[i] = expression
+	}
+
+	return CoalesceExpression{values: values}, nil
+}
+
+func empty(value any) bool {
+	switch value := value.(type) {
+	case string:
+		return value == ""
+	case []string:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package expressions
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   map[string]ExpressionFactory
+)
+
+// Register makes an expression type available to BuildExpression, usually
+// from the init function of a custom build. It panics when the type is
+// already registered or the factory is nil.
+func Register(typ string, factory ExpressionFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("expressions: Register factory is nil for " + typ)
+	}
+	if _, dup := registry[typ]; dup {
+		panic("expressions: Register called twice for " + typ)
+	}
+	registry[typ] = factory
+}
+
+// Lookup returns the factory registered for an expression type.
+func Lookup(typ string) (ExpressionFactory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[typ]
+	return factory, ok
+}
+
+// Types returns the registered expression types, sorted.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for typ := range registry {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Parse builds the expression tree of a JSON block like BuildExpression,
+// also returning as errors the panics of custom factories.
+func Parse(data []byte) (expression Expression, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			expression, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	return BuildExpression(data)
+}
+
+// Validate parses a JSON block and checks it evaluates to the given kind,
+// reflect.Bool for mapping params.
+func Validate(data []byte, kind reflect.Kind) error {
+	expression, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	if expression.ReturnType() != kind {
+		return fmt.Errorf("expression evaluates to %s, not %s", expression.ReturnType(), kind)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package expressions
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/signing"
+)
+
+// RawBodyValueExpression is the request body as received, e.g. to check
+// its signature.
+type RawBodyValueExpression struct{}
+
+func (e RawBodyValueExpression) Evaluate(fetchers EvaluationFetchers) any {
+	return fetchers.RawBodyFetcher()
+}
+
+func (e RawBodyValueExpression) ReturnType() reflect.Kind {
+	return reflect.String
+}
+
+func rawBodyValueFactory(body map[string]json.RawMessage) (Expression, error) {
+	return RawBodyValueExpression{}, nil
+}
+
+// HMACExpression signs a value, the raw body unless told otherwise, with
+// prefix prepended to the signature, like "sha256=" for GitHub webhooks.
+type HMACExpression struct {
+	algorithm string
+	secret    string
+	encoding  string
+	prefix    string
+	value     Expression
+}
+
+func (e HMACExpression) Evaluate(fetchers EvaluationFetchers) any {
+	signature, _ := signing.HMAC(e.algorithm, e.secret, []byte(e.value.Evaluate(fetchers).(string)), e.encoding)
+	return e.prefix + signature
+}
+
+func (e HMACExpression) ReturnType() reflect.Kind {
+	return reflect.String
+}
+
+func hmacFactory(body map[string]json.RawMessage) (Expression, error) {
+	expression := HMACExpression{algorithm: "sha256", value: RawBodyValueExpression{}}
+	if body["secret"] == nil {
+		return nil, errors.New("invalid block: HMAC must have secret attribute")
+	}
+	for key, field := range map[string]*string{"secret": &expression.secret, "algorithm": &expression.algorithm, "encoding": &expression.encoding, "prefix": &expression.prefix} {
+		if body[key] == nil {
+			continue
+		}
+		value, err := parseJsonString(body[key])
+		if err != nil {
+			return nil, err
+		}
+		*field = value
+	}
+	if err := signing.Check(expression.algorithm, expression.encoding); err != nil {
+		return nil, err
+	}
+
+	if body["value"] != nil {
+		value, err := BuildExpression(body["value"])
+		if err != nil {
+			return nil, err
+		}
+		if value.ReturnType() != reflect.String {
+			return nil, errors.New("invalid blocks: HMAC value must be a string")
+		}
+		expression.value = value
+	}
+	return expression, nil
+}
+
+var sigV4Components = map[string]func(signing.SigV4) string{
+	"accessKey":     func(s signing.SigV4) string { return s.AccessKey },
+	"date":          func(s signing.SigV4) string { return s.Date },
+	"region":        func(s signing.SigV4) string { return s.Region },
+	"service":       func(s signing.SigV4) string { return s.Service },
+	"signedHeaders": func(s signing.SigV4) string { return strings.Join(s.SignedHeaders, ";") },
+	"signature":     func(s signing.SigV4) string { return s.Signature },
+}
+
+// SigV4Expression reads a component of an AWS SigV4 Authorization header,
+// empty when the request is not signed that way.
+type SigV4Expression struct {
+	component string
+}
+
+func (e SigV4Expression) Evaluate(fetchers EvaluationFetchers) any {
+	parsed, ok := signing.ParseSigV4(fetchers.HeaderFetcher("Authorization"))
+	if !ok {
+		return ""
+	}
+	return sigV4Components[e.component](parsed)
+}
+
+func (e SigV4Expression) ReturnType() reflect.Kind {
+	return reflect.String
+}
+
+func sigV4Factory(body map[string]json.RawMessage) (Expression, error) {
+	if body["component"] == nil {
+		return nil, errors.New("invalid block: SIGV4 must have component attribute")
+	}
+	component, err := parseJsonString(body["component"])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sigV4Components[component]; !ok {
+		return nil, errors.New("unknown SIGV4 component " + component)
+	}
+	return SigV4Expression{component: component}, nil
+}
+
+// SigV4ValidExpression holds when the AWS SigV4 signature of the request
+// was made with secret.
+type SigV4ValidExpression struct {
+	secret string
+}
+
+func (e SigV4ValidExpression) Evaluate(fetchers EvaluationFetchers) any {
+	parsed, ok := signing.ParseSigV4(fetchers.HeaderFetcher("Authorization"))
+	if !ok {
+		return false
+	}
+	return parsed.Verify(e.secret, signing.SigV4Request{
+		Method:  fetchers.MethodFetcher(),
+		URL:     fetchers.URLFetcher(),
+		Host:    fetchers.HostFetcher(),
+		Headers: fetchers.HeaderArrayFetcher,
+		Body:    fetchers.RawBodyFetcher(),
+	})
+}
+
+func (e SigV4ValidExpression) ReturnType() reflect.Kind {
+	return reflect.Bool
+}
+
+func sigV4ValidFactory(body map[string]json.RawMessage) (Expression, error) {
+	if body["secret"] == nil {
+		return nil, errors.New("invalid block: SIGV4_VALID must have secret attribute")
+	}
+	secret, err := parseJsonString(body["secret"])
+	if err != nil {
+		return nil, err
+	}
+	return SigV4ValidExpression{secret: secret}, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replayedHeaders are left out of replayed requests, the client sets them
+// for the target.
+var replayedHeaders = []string{"Host", "Content-Length", "Connection", "Transfer-Encoding"}
+
+func replayCommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := flags.String("target", "", "base URL to send the recorded requests to, e.g. http://localhost:8080")
+	speed := flags.Float64("speed", 1, "replay this many times faster than recorded, 0 sends the requests one after the other without waiting")
+	server := flags.String("server", "", "only replay the requests received by this server")
+	timeout := flags.Duration("timeout", 30*time.Second, "give up on requests taking longer than this")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || *target == "" {
+		fmt.Println("Usage: doppelganger replay -target <url> [options] <journal_file|har_file>")
+		return 2
+	}
+	base, err := url.Parse(*target)
+	if err != nil {
+		fmt.Printf("Error parsing target: %s\n", err)
+		return 2
+	}
+
+	recordings, err := readRecordings(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading recordings: %s\n", err)
+		return 2
+	}
+	var requests []recording
+	for _, recording := range recordings {
+		if recording.Protocol == "" && (*server == "" || recording.Server == *server) {
+			requests = append(requests, recording)
+		}
+	}
+
+	client := &http.Client{Timeout: *timeout, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var differing, failed int
+	start := time.Now()
+	for _, request := range requests {
+		if *speed > 0 && !request.Time.IsZero() && !requests[0].Time.IsZero() {
+			offset := time.Duration(float64(request.Time.Sub(requests[0].Time)) / *speed)
+			time.Sleep(time.Until(start.Add(offset)))
+		}
+
+		replay := func() {
+			status, elapsed, err := replayRequest(client, base, request)
+
+			mu.Lock()
+			defer mu.Unlock()
+			line := fmt.Sprintf("%s %s", request.Method, request.Path)
+			if request.Query != "" {
+				line += "?" + request.Query
+			}
+			switch {
+			case err != nil:
+				failed++
+				fmt.Printf("%s failed: %s\n", line, err)
+			case request.Status != 0 && status != request.Status:
+				differing++
+				fmt.Printf("%s -> %d, recorded %d (%s)\n", line, status, request.Status, elapsed.Round(time.Millisecond))
+			default:
+				fmt.Printf("%s -> %d (%s)\n", line, status, elapsed.Round(time.Millisecond))
+			}
+		}
+		if *speed == 0 {
+			replay()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replay()
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("Replayed %d requests in %s, %d answered with another status than recorded, %d failed\n",
+		len(requests), time.Since(start).Round(time.Millisecond), differing, failed)
+	if differing > 0 || failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replayRequest sends a recorded request to the target, returning the
+// status it answered with.
+func replayRequest(client *http.Client, base *url.URL, request recording) (int, time.Duration, error) {
+	target := base.JoinPath(request.Path)
+	target.RawQuery = request.Query
+
+	outgoing, err := http.NewRequest(request.Method, target.String(), strings.NewReader(request.Body))
+	if err != nil {
+		return 0, 0, err
+	}
+	for name, values := range request.Headers {
+		outgoing.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	for _, name := range replayedHeaders {
+		outgoing.Header.Del(name)
+	}
+
+	sent := time.Now()
+	response, err := client.Do(outgoing)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	return response.StatusCode, time.Since(sent), nil
+}
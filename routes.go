@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+)
+
+func routesCommand(args []string) int {
+	flags := flag.NewFlagSet("routes", flag.ExitOnError)
+	loadFlags := registerLoadFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger routes [options] <json_file>")
+		return 2
+	}
+
+	servers, _, err := loadConfiguration(flags.Arg(0), loadFlags)
+	if err != nil {
+		fmt.Printf("Error parsing configuration: %s\n", err)
+		return 2
+	}
+
+	printRoutes(os.Stdout, servers)
+	return 0
+}
+
+func printRoutes(out io.Writer, servers *config.Servers) {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SERVER\tPORT\tVERB\tPATH\tMAPPINGS\tCODES")
+
+	for _, configuration := range servers.Configurations {
+		switch configuration.Type {
+		case config.ServerTypeTCP:
+			fmt.Fprintf(writer, "%s\t%d\tTCP\t-\t%d\t-\n", configuration.Name, configuration.Port, len(configuration.Rules))
+			continue
+		case config.ServerTypeSMTP, config.ServerTypeUDP:
+			fmt.Fprintf(writer, "%s\t%d\t%s\t-\t-\t-\n", configuration.Name, configuration.Port, configuration.Type)
+			continue
+		}
+		for _, endpoint := range configuration.Endpoints {
+			codes := make([]string, len(endpoint.Mappings))
+			for j, mapping := range endpoint.Mappings {
+				codes[j] = strconv.Itoa(mapping.RespCode)
+			}
+
+			fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%d\t%s\n",
+				configuration.Name, configuration.Port, endpoint.Verb, endpoint.Path, len(endpoint.Mappings), strings.Join(codes, ","))
+		}
+	}
+
+	writer.Flush()
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+)
+
+func schemaCommand(args []string) int {
+	fmt.Print(config.Schema)
+	return 0
+}
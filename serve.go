@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dsa-ferreira/doppelganger/internal/admin"
+	"github.com/dsa-ferreira/doppelganger/internal/config"
+	"github.com/dsa-ferreira/doppelganger/internal/events"
+	"github.com/dsa-ferreira/doppelganger/internal/filecache"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+	"github.com/dsa-ferreira/doppelganger/internal/kv"
+	"github.com/dsa-ferreira/doppelganger/internal/logging"
+	"github.com/dsa-ferreira/doppelganger/internal/metrics"
+	"github.com/dsa-ferreira/doppelganger/internal/openapi"
+	"github.com/dsa-ferreira/doppelganger/internal/quotas"
+	"github.com/dsa-ferreira/doppelganger/internal/scenarios"
+	"github.com/dsa-ferreira/doppelganger/internal/server"
+	"github.com/dsa-ferreira/doppelganger/internal/smtp"
+	"github.com/dsa-ferreira/doppelganger/internal/supervisor"
+	"github.com/dsa-ferreira/doppelganger/internal/systemd"
+	"github.com/dsa-ferreira/doppelganger/internal/tcp"
+	"github.com/dsa-ferreira/doppelganger/internal/toggles"
+	"github.com/dsa-ferreira/doppelganger/internal/udp"
+	"github.com/dsa-ferreira/doppelganger/internal/uploads"
+	"github.com/dsa-ferreira/doppelganger/internal/usage"
+	"github.com/dsa-ferreira/doppelganger/internal/variants"
+)
+
+// stopRequests lets a service manager without signals, as on Windows,
+// shut serve down like SIGTERM does.
+var stopRequests = make(chan struct{})
+
+func serveCommand(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	verbose := flags.Bool("verbose", false, "increase verbosity")
+	loadFlags := registerLoadFlags(flags)
+	dryRun := flags.Bool("dry-run", false, "print the routing table and exit without binding ports")
+	logOutput := flags.String("log-output", "stdout", "where to write logs: stdout, stderr, file:<path>, syslog or journald")
+	logMaxSize := flags.Int64("log-max-size", 100, "size in MB after which file logs are rotated, 0 disables rotation")
+	logMaxBackups := flags.Int("log-max-backups", 3, "number of rotated log files to keep")
+	journalFile := flags.String("journal", "", "append unmatched requests as JSON lines to this file")
+	journalMatched := flags.Bool("journal-matched", false, "also append matched requests to the journal file")
+	journalMaxEntries := flags.Int("journal-max-entries", 10000, "requests the admin API keeps in memory before dropping the oldest, 0 for no limit")
+	journalMaxSize := flags.Int64("journal-max-size", 100, "size in MB of the requests the admin API keeps in memory before dropping the oldest, 0 for no limit")
+	adminPort := flags.Int("admin-port", 0, "serve the admin API on this port, 0 disables it")
+	fileCacheSize := flags.Int64("file-cache-size", 64, "memory in MB used to cache FILE responses, 0 disables the cache")
+	openapiFile := flags.String("openapi", "", "make up responses from this OpenAPI 3 spec for requests no mapping answers")
+	openapiStrict := flags.Bool("openapi-strict", false, "answer responses not matching the -openapi spec with a 500 instead of logging them")
+	strict := flags.Bool("strict", false, "answer unmatched requests with a 501 and exit with status 1 listing them on shutdown")
+	slowThreshold := flags.Duration("slow-threshold", 0, "log requests slower than this, excluding configured delays (e.g. 200ms)")
+	seed := flags.Uint64("seed", 0, "seed random values, such as upload session ids, so runs can be reproduced")
+	readyFile := flags.String("ready-file", "", "write the process id to this file once every server listens, and remove it on shutdown")
+	var allowExec listFlag
+	flags.Var(&allowExec, "allow-exec", "comma separated list of commands EXEC content may run")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger serve [options] <json_file>")
+		return 2
+	}
+
+	servers, report, err := loadConfiguration(flags.Arg(0), loadFlags)
+	if err != nil {
+		fmt.Printf("Error parsing configuration: %s\n", err)
+		return 2
+	}
+
+	if *dryRun {
+		printRoutes(os.Stdout, servers)
+		return 0
+	}
+
+	logs, err := logging.Setup(logging.Options{Output: *logOutput, MaxSize: *logMaxSize << 20, MaxBackups: *logMaxBackups})
+	if err != nil {
+		fmt.Printf("Error setting up logging: %s\n", err)
+		return 2
+	}
+	defer logs.Close()
+	build := buildInfo()
+	log.Printf("doppelganger %s serving %s, config sha256 %s\n", build.Version, strings.Join(report.files, ", "), report.hash)
+	log.Println(report)
+	for _, issue := range report.issues {
+		log.Println("Warning: " + issue.String())
+	}
+
+	options := server.Options{Verbose: *verbose, Metrics: metrics.NewRegistry(), SlowThreshold: *slowThreshold, Events: events.NewQueue(), Usage: usage.NewTracker(), Scenarios: scenarios.NewStore(), Quotas: quotas.NewStore(), Variants: variants.NewStore(), Uploads: uploads.NewStore(), Toggles: toggles.NewStore(), KV: kv.NewStore(), AllowExec: allowExec}
+	if *openapiFile != "" {
+		options.OpenAPIStrict = *openapiStrict
+		options.OpenAPI, err = openapi.Load(*openapiFile)
+		if err != nil {
+			fmt.Printf("Error loading OpenAPI spec: %s\n", err)
+			return 2
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			options.Uploads.Seed(*seed)
+		}
+	})
+	if *fileCacheSize > 0 {
+		options.FileCache = filecache.New(*fileCacheSize << 20)
+	}
+	var recorders journal.Recorders
+	if *journalFile != "" {
+		recorder, err := journal.NewFileRecorder(*journalFile, *journalMatched)
+		if err != nil {
+			fmt.Printf("Error opening journal: %s\n", err)
+			return 2
+		}
+		defer recorder.Close()
+		recorders = append(recorders, recorder)
+	}
+	var requests *journal.MemoryRecorder
+	if *adminPort != 0 {
+		requests = journal.NewMemoryRecorder(journal.Limits{MaxEntries: *journalMaxEntries, MaxBytes: *journalMaxSize << 20})
+		recorders = append(recorders, requests)
+	}
+	if len(recorders) > 0 {
+		options.Journal = recorders
+	}
+
+	var unmatched *journal.MemoryRecorder
+	if *strict {
+		unmatched = journal.NewMemoryRecorder(journal.Limits{})
+		options.Unmatched = unmatched
+	}
+
+	sockets, err := systemd.Inherit()
+	if err != nil {
+		fmt.Printf("Error inheriting sockets: %s\n", err)
+		return 2
+	}
+	options.Sockets = sockets
+
+	mailbox := smtp.NewMailbox()
+	running := supervisor.New(sockets)
+	for i := 0; i < len(servers.Configurations); i++ {
+		configuration := &servers.Configurations[i]
+		var start supervisor.StartFunc
+		switch configuration.Type {
+		case config.ServerTypeTCP:
+			start = func() (io.Closer, error) {
+				return tcp.Listen(configuration, tcp.Options{Verbose: *verbose, Sockets: sockets})
+			}
+		case config.ServerTypeSMTP:
+			start = func() (io.Closer, error) {
+				return smtp.Listen(configuration, smtp.Options{Verbose: *verbose, Mailbox: mailbox, Sockets: sockets})
+			}
+		case config.ServerTypeUDP:
+			start = func() (io.Closer, error) {
+				return udp.Listen(configuration, udp.Options{Verbose: *verbose, Journal: options.Journal, Sockets: sockets})
+			}
+		default:
+			start = func() (io.Closer, error) {
+				return server.Listen(configuration, options)
+			}
+		}
+		network := "tcp"
+		if configuration.Type == config.ServerTypeUDP {
+			network = "udp"
+		}
+		running.Add(configuration.Name, network, configuration.Port, start)
+	}
+	for _, configuration := range servers.Configurations {
+		if configuration.StandbyFor == "" {
+			continue
+		}
+		if err := running.Standby(configuration.Name, configuration.StandbyFor); err != nil {
+			fmt.Printf("Error starting servers: %s\n", err)
+			return 2
+		}
+	}
+	if err := running.StartAll(); err != nil {
+		fmt.Printf("Error starting servers: %s\n", err)
+		return 2
+	}
+	if *adminPort != 0 {
+		_, err := admin.Listen(*adminPort, admin.Options{Metrics: options.Metrics, Mailbox: mailbox, Journal: requests, Events: options.Events, Usage: options.Usage, Scenarios: options.Scenarios, Uploads: options.Uploads, Toggles: options.Toggles, Quotas: options.Quotas, Variants: options.Variants, KV: options.KV, Servers: running, Build: build, Info: admin.Info{Version: build.Version, Files: report.files, ConfigHash: report.hash, LoadedAt: report.loadedAt}})
+		if err != nil {
+			fmt.Printf("Error starting admin API: %s\n", err)
+			return 2
+		}
+	}
+	log.Printf("Ready, %d servers listening\n", running.Listening())
+	if *readyFile != "" {
+		if err := os.WriteFile(*readyFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+			fmt.Printf("Error writing ready file: %s\n", err)
+			return 2
+		}
+		defer os.Remove(*readyFile)
+	}
+
+	gracefulShutdown := make(chan os.Signal, 1)
+	signal.Notify(gracefulShutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-gracefulShutdown:
+	case <-stopRequests:
+	}
+
+	log.Println("Shuting down")
+	for _, mapping := range options.Usage.Unused() {
+		log.Printf("Mapping %s of endpoint %s on server %s was never used\n", mapping.Mapping, mapping.Endpoint, mapping.Server)
+	}
+	if unmatched != nil && len(unmatched.Entries()) > 0 {
+		return reportUnmatched(unmatched.Entries())
+	}
+	return 0
+}
+
+// reportUnmatched prints the requests no mapping answered as a JSON
+// summary for CI pipelines and fails the run.
+func reportUnmatched(entries []journal.Entry) int {
+	log.Printf("%d requests did not match any mapping\n", len(entries))
+	summary, _ := json.Marshal(map[string]any{"unmatched": entries})
+	fmt.Println(string(summary))
+	return 1
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// runService reports that the process is not a Windows service.
+func runService(args []string) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package main
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+// runService runs serve under the Windows service manager when started by
+// it, e.g. once created with
+// `sc create doppelganger binPath= "C:\doppelganger.exe serve -log-output file:C:\mocks.log C:\mocks.json"`.
+func runService(args []string) (int, bool) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return 0, false
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+
+	service := &windowsService{args: args}
+	if err := svc.Run("doppelganger", service); err != nil {
+		return 1, true
+	}
+	return service.code, true
+}
+
+type windowsService struct {
+	args []string
+	code int
+}
+
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	done := make(chan int, 1)
+	go func() {
+		done <- serveCommand(s.args)
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case s.code = <-done:
+			return s.code != 0, uint32(s.code)
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				changes <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				close(stopRequests)
+				s.code = <-done
+				return s.code != 0, uint32(s.code)
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dsa-ferreira/doppelganger/internal/har"
+	"github.com/dsa-ferreira/doppelganger/internal/journal"
+)
+
+type suggestedExpression struct {
+	Type  string               `json:"type"`
+	ID    string               `json:"id,omitempty"`
+	Value string               `json:"value,omitempty"`
+	Left  *suggestedExpression `json:"left,omitempty"`
+	Right *suggestedExpression `json:"right,omitempty"`
+}
+
+type suggestedContent struct {
+	Data any `json:"data"`
+}
+
+type suggestedMapping struct {
+	Description string                `json:"description"`
+	Params      []suggestedExpression `json:"params,omitempty"`
+	Code        int                   `json:"code"`
+	Delay       int                   `json:"delay,omitempty"`
+	Content     suggestedContent      `json:"content"`
+}
+
+type suggestedEndpoint struct {
+	Path     string              `json:"path"`
+	Verb     string              `json:"verb"`
+	Mappings []*suggestedMapping `json:"mappings"`
+
+	byQuery map[string]*suggestedMapping
+	hits    map[*suggestedMapping]int
+	elapsed map[*suggestedMapping][]time.Duration
+}
+
+type suggestedServer struct {
+	Name      string               `json:"name"`
+	Endpoints []*suggestedEndpoint `json:"endpoint"`
+
+	byRoute map[string]*suggestedEndpoint
+}
+
+func suggestCommand(args []string) int {
+	flags := flag.NewFlagSet("suggest", flag.ExitOnError)
+	all := flags.Bool("all", false, "also suggest stubs for requests that were matched")
+	delays := flags.Bool("delays", false, "keep the median response time of HAR recordings as mapping delays")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger suggest [options] <journal_file|har_file>")
+		return 2
+	}
+
+	recordings, err := readRecordings(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading recordings: %s\n", err)
+		return 2
+	}
+
+	servers := suggestStubs(recordings, *all, *delays)
+
+	var output any = map[string]any{"servers": servers}
+	if len(servers) == 1 {
+		output = map[string]any{"endpoint": servers[0].Endpoints}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(output); err != nil {
+		fmt.Printf("Error writing suggestions: %s\n", err)
+		return 2
+	}
+	return 0
+}
+
+// recording is a request to draft stubs for. Those read from HAR files also
+// come with what the real service answered and how long it took.
+type recording struct {
+	journal.Entry
+	response *har.Response
+	elapsed  time.Duration
+}
+
+// readRecordings reads a journal, or a HAR file when it has the extension.
+func readRecordings(path string) ([]recording, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".har") {
+		entries, err := journal.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		recordings := make([]recording, len(entries))
+		for i, entry := range entries {
+			recordings[i] = recording{Entry: entry}
+		}
+		return recordings, nil
+	}
+
+	file, err := har.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recordings := make([]recording, 0, len(file.Log.Entries))
+	for _, entry := range file.Log.Entries {
+		target, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request URL %s: %w", entry.Request.URL, err)
+		}
+		headers := map[string][]string{}
+		for _, header := range entry.Request.Headers {
+			headers[header.Name] = append(headers[header.Name], header.Value)
+		}
+		var body string
+		if entry.Request.PostData != nil {
+			body = entry.Request.PostData.Text
+		}
+		// Waiting is the time the service took, without the network.
+		elapsed := entry.Timings.Wait
+		if elapsed <= 0 {
+			elapsed = entry.Time
+		}
+
+		started, _ := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+		recordings = append(recordings, recording{
+			Entry: journal.Entry{
+				Time:    started,
+				Server:  target.Host,
+				Method:  entry.Request.Method,
+				Path:    target.Path,
+				Query:   target.RawQuery,
+				Headers: headers,
+				Body:    body,
+				Status:  entry.Response.Status,
+			},
+			response: &entry.Response,
+			elapsed:  time.Duration(elapsed * float64(time.Millisecond)),
+		})
+	}
+	return recordings, nil
+}
+
+// suggestStubs groups recordings by server, verb and path and drafts one
+// mapping per distinct set of query parameters, answering like the real
+// service when it is known.
+func suggestStubs(recordings []recording, all bool, delays bool) []*suggestedServer {
+	var servers []*suggestedServer
+	byName := map[string]*suggestedServer{}
+
+	for _, entry := range recordings {
+		if entry.Matched && !all || entry.Protocol != "" {
+			continue
+		}
+
+		srv, ok := byName[entry.Server]
+		if !ok {
+			srv = &suggestedServer{Name: entry.Server, byRoute: map[string]*suggestedEndpoint{}}
+			byName[entry.Server] = srv
+			servers = append(servers, srv)
+		}
+
+		route := entry.Method + " " + entry.Path
+		endpoint, ok := srv.byRoute[route]
+		if !ok {
+			endpoint = &suggestedEndpoint{
+				Path:    entry.Path,
+				Verb:    entry.Method,
+				byQuery: map[string]*suggestedMapping{},
+				hits:    map[*suggestedMapping]int{},
+				elapsed: map[*suggestedMapping][]time.Duration{},
+			}
+			srv.byRoute[route] = endpoint
+			srv.Endpoints = append(srv.Endpoints, endpoint)
+		}
+
+		query, _ := url.ParseQuery(entry.Query)
+		key := query.Encode()
+		mapping, ok := endpoint.byQuery[key]
+		if !ok {
+			mapping = &suggestedMapping{
+				Params: queryParams(query),
+				Code:   200,
+				Content: suggestedContent{Data: map[string]string{
+					"TODO": "response for " + strings.TrimSuffix(route+"?"+key, "?"),
+				}},
+			}
+			if entry.response != nil {
+				mapping.Code = entry.response.Status
+				if data, ok := recordedData(entry.response); ok {
+					mapping.Content.Data = data
+				}
+			}
+			endpoint.byQuery[key] = mapping
+			endpoint.Mappings = append(endpoint.Mappings, mapping)
+		}
+		endpoint.hits[mapping]++
+		if entry.response != nil {
+			endpoint.elapsed[mapping] = append(endpoint.elapsed[mapping], entry.elapsed)
+		}
+		mapping.Description = fmt.Sprintf("Suggested from %d recorded request(s)", endpoint.hits[mapping])
+	}
+
+	for _, srv := range servers {
+		for _, endpoint := range srv.Endpoints {
+			if delays {
+				for _, mapping := range endpoint.Mappings {
+					mapping.Delay = int(median(endpoint.elapsed[mapping]).Milliseconds())
+				}
+			}
+			// Mappings with params go first so they are not shadowed by a catch-all.
+			sort.SliceStable(endpoint.Mappings, func(i, j int) bool {
+				return len(endpoint.Mappings[i].Params) > len(endpoint.Mappings[j].Params)
+			})
+		}
+	}
+
+	return servers
+}
+
+// recordedData is the JSON body of a recorded response.
+func recordedData(response *har.Response) (any, bool) {
+	if !strings.Contains(response.Content.MimeType, "json") {
+		return nil, false
+	}
+	body, err := response.Content.Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var data any
+	if json.Unmarshal(body, &data) != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+func queryParams(query url.Values) []suggestedExpression {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]suggestedExpression, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, suggestedExpression{
+			Type:  "EQUALS",
+			Left:  &suggestedExpression{Type: "QUERY", ID: key},
+			Right: &suggestedExpression{Type: "STRING", Value: query.Get(key)},
+		})
+	}
+	return params
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func validateCommand(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	loadFlags := registerLoadFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: doppelganger validate [options] <json_file>")
+		return 2
+	}
+
+	_, report, err := loadConfiguration(flags.Arg(0), loadFlags)
+	if err != nil {
+		fmt.Printf("Error parsing configuration: %s\n", err)
+		return 2
+	}
+
+	if len(report.issues) > 0 {
+		for _, issue := range report.issues {
+			fmt.Println(issue)
+		}
+		fmt.Printf("Found %d problems in the configuration\n", len(report.issues))
+		return 2
+	}
+
+	fmt.Println("Configuration OK. " + report.String())
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/dsa-ferreira/doppelganger/internal/admin"
+)
+
+// version is overridden at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
+
+func versionCommand(args []string) int {
+	build := buildInfo()
+	var details []string
+	if build.Commit != "" {
+		commit := build.Commit
+		if build.Modified {
+			commit += "-dirty"
+		}
+		details = append(details, "commit "+commit)
+	}
+	if build.Date != "" {
+		details = append(details, "committed "+build.Date)
+	}
+	details = append(details, build.GoVersion)
+	fmt.Printf("doppelganger %s (%s)\n", build.Version, strings.Join(details, ", "))
+	return 0
+}
+
+// buildInfo completes version with what Go records in the binary: the
+// module version when installed with go install, and the commit and its
+// date when built from a checkout.
+func buildInfo() admin.Build {
+	build := admin.Build{Version: version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.GoVersion = info.GoVersion
+	if build.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		build.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.Date = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}